@@ -0,0 +1,229 @@
+package budget
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+
+	stats "callstat/Statistics"
+)
+
+/* ============================================================================
+ * Budget
+ * ----------------------------------------------------------------------------
+ * One threshold of a budget file.
+ *
+ *   Name  shown in the pass/fail table (the expression if empty)
+ *   Expr  boolean expression over the stats JSON (see Evaluate), e.g.
+ *         "indirect.funcVarCallSites / grandTotal.total < 0.1"
+ * ============================================================================
+ */
+type Budget struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+
+	expr ast.Expr
+}
+
+/* ============================================================================
+ * Set
+ * ----------------------------------------------------------------------------
+ * The contents of a budget file:
+ *
+ *   {
+ *     "budgets": [
+ *       { "name": "func-var share",
+ *         "expr": "indirect.funcVarCallSites / grandTotal.total < 0.1" },
+ *       { "name": "unused in project",
+ *         "expr": "sum(pkgs(\"github.com/you/repo/\"), \"unusedFunctions\") <= 25" }
+ *     ]
+ *   }
+ * ============================================================================
+ */
+type Set struct {
+	Budgets []*Budget `json:"budgets"`
+}
+
+/* ============================================================================
+ * Load
+ * ----------------------------------------------------------------------------
+ * Reads a budget file and parses every expression, so syntax errors show
+ * up before the analysis runs.
+ * ============================================================================
+ */
+func Load(path string) (*Set, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set Set
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	for i, b := range set.Budgets {
+		if b.Expr == "" {
+			return nil, fmt.Errorf("%s: budget %d has no expression", path, i+1)
+		}
+		if b.Name == "" {
+			b.Name = b.Expr
+		}
+		if b.expr, err = parser.ParseExpr(b.Expr); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, b.Name, err)
+		}
+	}
+	return &set, nil
+}
+
+/* ============================================================================
+ * Outcome
+ * ----------------------------------------------------------------------------
+ * How a budget fared.
+ *
+ *   OutcomePass   the expression is true
+ *   OutcomeFail   the expression is false
+ *   OutcomeError  the expression could not be evaluated (unknown field,
+ *                 type mismatch, division by zero, non-boolean result)
+ * ============================================================================
+ */
+type Outcome int
+
+const (
+	OutcomePass Outcome = iota
+	OutcomeFail
+	OutcomeError
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomePass:  return "pass"
+	case OutcomeFail:  return "fail"
+	case OutcomeError: return "error"
+	default:           return "unknown"
+	}
+}
+
+func (o Outcome) MarshalText() ([]byte, error) { return []byte(o.String()), nil }
+
+/* ============================================================================
+ * Result
+ * ----------------------------------------------------------------------------
+ *   Name     Budget.Name
+ *   Expr     Budget.Expr
+ *   Value    the evaluated expression; for a comparison both sides, e.g.
+ *            "0.034 < 0.1"
+ *   Outcome  pass, fail or error
+ *   Error    why the expression could not be evaluated
+ * ============================================================================
+ */
+type Result struct {
+	Name    string  `json:"name"`
+	Expr    string  `json:"expr"`
+	Value   string  `json:"value"`
+	Outcome Outcome `json:"outcome"`
+	Error   string  `json:"error,omitempty"`
+}
+
+/* ============================================================================
+ * Evaluate
+ * ----------------------------------------------------------------------------
+ * Evaluates every budget of set against report. Expressions use Go syntax
+ * over the report's JSON form, so names are the stats JSON keys:
+ *
+ *   indirect.funcVarCallSites          field access (unknown field = error)
+ *   grandTotal.counts["reflect"]       map lookup (absent key = 0)
+ *   packages["ex/util"].functionCount
+ *   + - * / %  < <= > >= == !=  && || !  ( )
+ *
+ * and these functions:
+ *
+ *   len(x)                  length of a list, object or string
+ *   pkgs(patterns...)       packages matching -skip-cg style patterns ("std"
+ *                           = the standard library); none = all packages
+ *   except(list, patterns...)  list without the matching packages
+ *   depth(list, d)          packages of list at depth d
+ *   sum(list, "field")      sum of a field over list; list fields count
+ *                           their length
+ * ============================================================================
+ */
+func Evaluate(set *Set, report *stats.CallGraphReport) ([]*Result, error) {
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	var root map[string]any
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, err
+	}
+	ev := &evaluator{root: root}
+
+	results := []*Result{}
+	for _, b := range set.Budgets {
+		results = append(results, ev.run(b))
+	}
+	return results, nil
+}
+
+/* ============================================================================
+ * Worst
+ * ----------------------------------------------------------------------------
+ * Returns the worst outcome among results (OutcomePass if there are none),
+ * which doubles as the process exit code: 0 pass, 1 fail, 2 error.
+ * ============================================================================
+ */
+func Worst(results []*Result) Outcome {
+	worst := OutcomePass
+	for _, r := range results {
+		worst = max(worst, r.Outcome)
+	}
+	return worst
+}
+
+func (ev *evaluator) run(b *Budget) *Result {
+	r := &Result{Name: b.Name, Expr: b.Expr}
+
+	v, err := ev.eval(b.expr)
+	if err == nil {
+		if pass, ok := v.(bool); !ok {
+			err = fmt.Errorf("expression is %s, not a boolean", describe(v))
+		} else if pass {
+			r.Outcome = OutcomePass
+		} else {
+			r.Outcome = OutcomeFail
+		}
+	}
+	if err != nil {
+		r.Outcome = OutcomeError
+		r.Error   = err.Error()
+		return r
+	}
+
+	r.Value = format(v)
+	if cmp, ok := unparen(b.expr).(*ast.BinaryExpr); ok && isComparison(cmp.Op) {
+		x, _ := ev.eval(cmp.X)
+		y, _ := ev.eval(cmp.Y)
+		r.Value = fmt.Sprintf("%s %s %s", format(x), cmp.Op, format(y))
+	}
+	return r
+}
+
+func unparen(x ast.Expr) ast.Expr {
+	for {
+		p, ok := x.(*ast.ParenExpr)
+		if !ok {
+			return x
+		}
+		x = p.X
+	}
+}
+
+func isComparison(op token.Token) bool {
+	switch op {
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return true
+	}
+	return false
+}
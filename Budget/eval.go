@@ -0,0 +1,395 @@
+package budget
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"math"
+	"sort"
+	"strconv"
+
+	cs_callgraph "callstat/CS-Callgraph"
+)
+
+/* ============================================================================
+ * evaluator
+ * ----------------------------------------------------------------------------
+ * Walks a parsed expression over the report decoded into plain JSON values:
+ * float64, string, bool, []any, map[string]any and nil.
+ * ============================================================================
+ */
+type evaluator struct {
+	root map[string]any
+}
+
+func (ev *evaluator) eval(x ast.Expr) (any, error) {
+	switch x := x.(type) {
+	case *ast.ParenExpr:
+		return ev.eval(x.X)
+
+	case *ast.BasicLit:
+		switch x.Kind {
+		case token.INT, token.FLOAT:
+			return strconv.ParseFloat(x.Value, 64)
+		case token.STRING:
+			return strconv.Unquote(x.Value)
+		}
+		return nil, fmt.Errorf("unsupported literal %s", x.Value)
+
+	case *ast.Ident:
+		switch x.Name {
+		case "true":  return true, nil
+		case "false": return false, nil
+		}
+		v, ok := ev.root[x.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", x.Name)
+		}
+		return v, nil
+
+	case *ast.SelectorExpr:
+		obj, err := ev.eval(x.X)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := obj.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf(".%s on %s", x.Sel.Name, describe(obj))
+		}
+		v, ok := m[x.Sel.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", x.Sel.Name)
+		}
+		return v, nil
+
+	case *ast.IndexExpr:
+		return ev.index(x)
+
+	case *ast.UnaryExpr:
+		v, err := ev.eval(x.X)
+		if err != nil {
+			return nil, err
+		}
+		switch x.Op {
+		case token.NOT:
+			if b, ok := v.(bool); ok {
+				return !b, nil
+			}
+		case token.SUB:
+			if f, ok := v.(float64); ok {
+				return -f, nil
+			}
+		case token.ADD:
+			if f, ok := v.(float64); ok {
+				return f, nil
+			}
+		}
+		return nil, fmt.Errorf("%s on %s", x.Op, describe(v))
+
+	case *ast.BinaryExpr:
+		return ev.binary(x)
+
+	case *ast.CallExpr:
+		return ev.call(x)
+	}
+	return nil, fmt.Errorf("unsupported expression %T", x)
+}
+
+/* -------------------------------------------------------
+ * index
+ * obj["key"] is 0 for an absent key (maps such as counts
+ * omit zero entries); list[i] must be in range.
+ * ------------------------------------------------------- */
+func (ev *evaluator) index(x *ast.IndexExpr) (any, error) {
+	obj, err := ev.eval(x.X)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ev.eval(x.Index)
+	if err != nil {
+		return nil, err
+	}
+	switch obj := obj.(type) {
+	case map[string]any:
+		k, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("object index must be a string, not %s", describe(key))
+		}
+		if v, ok := obj[k]; ok {
+			return v, nil
+		}
+		return 0.0, nil
+	case []any:
+		i, ok := key.(float64)
+		if !ok || i != math.Trunc(i) || i < 0 || int(i) >= len(obj) {
+			return nil, fmt.Errorf("index %s out of range [0,%d)", format(key), len(obj))
+		}
+		return obj[int(i)], nil
+	}
+	return nil, fmt.Errorf("cannot index %s", describe(obj))
+}
+
+/* -------------------------------------------------------
+ * binary
+ * ------------------------------------------------------- */
+func (ev *evaluator) binary(x *ast.BinaryExpr) (any, error) {
+	lhs, err := ev.eval(x.X)
+	if err != nil {
+		return nil, err
+	}
+
+	// && and || short-circuit
+	if x.Op == token.LAND || x.Op == token.LOR {
+		l, ok := lhs.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s on %s", x.Op, describe(lhs))
+		}
+		if l == (x.Op == token.LOR) {
+			return l, nil
+		}
+		rhs, err := ev.eval(x.Y)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := rhs.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s on %s", x.Op, describe(rhs))
+		}
+		return r, nil
+	}
+
+	rhs, err := ev.eval(x.Y)
+	if err != nil {
+		return nil, err
+	}
+	switch x.Op {
+	case token.EQL: return equal(lhs, rhs)
+	case token.NEQ:
+		eq, err := equal(lhs, rhs)
+		if err != nil {
+			return nil, err
+		}
+		return !eq.(bool), nil
+	}
+
+	l, lok := lhs.(float64)
+	r, rok := rhs.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%s between %s and %s", x.Op, describe(lhs), describe(rhs))
+	}
+	switch x.Op {
+	case token.ADD: return l + r, nil
+	case token.SUB: return l - r, nil
+	case token.MUL: return l * r, nil
+	case token.QUO, token.REM:
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero in %s", types.ExprString(x))
+		}
+		if x.Op == token.QUO {
+			return l / r, nil
+		}
+		return math.Mod(l, r), nil
+	case token.LSS: return l < r, nil
+	case token.LEQ: return l <= r, nil
+	case token.GTR: return l > r, nil
+	case token.GEQ: return l >= r, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %s", x.Op)
+}
+
+func equal(lhs, rhs any) (any, error) {
+	switch l := lhs.(type) {
+	case float64:
+		if r, ok := rhs.(float64); ok {
+			return l == r, nil
+		}
+	case string:
+		if r, ok := rhs.(string); ok {
+			return l == r, nil
+		}
+	case bool:
+		if r, ok := rhs.(bool); ok {
+			return l == r, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot compare %s and %s", describe(lhs), describe(rhs))
+}
+
+/* -------------------------------------------------------
+ * call
+ * The builtins listed at Evaluate.
+ * ------------------------------------------------------- */
+func (ev *evaluator) call(x *ast.CallExpr) (any, error) {
+	fn, ok := x.Fun.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("unsupported call %s", types.ExprString(x.Fun))
+	}
+	args := make([]any, len(x.Args))
+	for i, a := range x.Args {
+		v, err := ev.eval(a)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	switch fn.Name {
+	case "len":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("len takes one argument")
+		}
+		switch v := args[0].(type) {
+		case []any:          return float64(len(v)), nil
+		case map[string]any: return float64(len(v)), nil
+		case string:         return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("len of %s", describe(args[0]))
+
+	case "pkgs":
+		patterns, err := stringArgs("pkgs", args)
+		if err != nil {
+			return nil, err
+		}
+		all := ev.packages()
+		if len(patterns) == 0 {
+			return all, nil
+		}
+		return filterPackages(all, func(pkg map[string]any) bool { return matches(pkg, patterns) }), nil
+
+	case "except":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("except takes a list and patterns")
+		}
+		list, ok := args[0].([]any)
+		if !ok {
+			return nil, fmt.Errorf("except on %s", describe(args[0]))
+		}
+		patterns, err := stringArgs("except", args[1:])
+		if err != nil {
+			return nil, err
+		}
+		return filterPackages(list, func(pkg map[string]any) bool { return !matches(pkg, patterns) }), nil
+
+	case "depth":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("depth takes a list and a depth")
+		}
+		list, ok := args[0].([]any)
+		d, dok  := args[1].(float64)
+		if !ok || !dok {
+			return nil, fmt.Errorf("depth(%s, %s)", describe(args[0]), describe(args[1]))
+		}
+		kept := []any{}
+		for _, item := range list {
+			if m, ok := item.(map[string]any); ok && m["depth"] == d {
+				kept = append(kept, item)
+			}
+		}
+		return kept, nil
+
+	case "sum":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("sum takes a list and a field name")
+		}
+		list, ok   := args[0].([]any)
+		field, fok := args[1].(string)
+		if !ok || !fok {
+			return nil, fmt.Errorf("sum(%s, %s)", describe(args[0]), describe(args[1]))
+		}
+		total := 0.0
+		for _, item := range list {
+			m, _ := item.(map[string]any)
+			switch v := m[field].(type) {
+			case float64: total += v
+			case []any:   total += float64(len(v))
+			case nil:
+			default:
+				return nil, fmt.Errorf("sum over %s field %q", describe(v), field)
+			}
+		}
+		return total, nil
+	}
+	return nil, fmt.Errorf("unknown function %s", fn.Name)
+}
+
+// packages returns the report's packages as a list sorted by path
+func (ev *evaluator) packages() []any {
+	m, _ := ev.root["packages"].(map[string]any)
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	list := make([]any, 0, len(paths))
+	for _, path := range paths {
+		list = append(list, m[path])
+	}
+	return list
+}
+
+func filterPackages(list []any, keep func(map[string]any) bool) []any {
+	kept := []any{}
+	for _, item := range list {
+		m, _ := item.(map[string]any)
+		if _, ok := m["path"].(string); ok && keep(m) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// matches is cs_callgraph.MatchesPattern plus "std" for the packages the
+// report marks isStdlib
+func matches(pkg map[string]any, patterns []string) bool {
+	path, _  := pkg["path"].(string)
+	isStd, _ := pkg["isStdlib"].(bool)
+	for _, p := range patterns {
+		if p == "std" && isStd {
+			return true
+		}
+	}
+	return cs_callgraph.MatchesPattern(path, patterns)
+}
+
+func stringArgs(fn string, args []any) ([]string, error) {
+	out := make([]string, len(args))
+	for i, a := range args {
+		s, ok := a.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: pattern must be a string, not %s", fn, describe(a))
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+/* -------------------------------------------------------
+ * describe / format
+ * ------------------------------------------------------- */
+func describe(v any) string {
+	switch v := v.(type) {
+	case nil:            return "null"
+	case float64:        return "number " + format(v)
+	case string:         return strconv.Quote(v)
+	case bool:           return "boolean"
+	case []any:          return fmt.Sprintf("list of %d", len(v))
+	case map[string]any: return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func format(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', 4, 64)
+	case []any:
+		return fmt.Sprintf("[%d]", len(v))
+	case map[string]any:
+		return "{…}"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,168 @@
+package budget
+
+import (
+	"encoding/json"
+	"go/parser"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testReport is a trimmed stats JSON, decoded the way Evaluate decodes it
+const testReport = `{
+	"indirect":   { "funcVarCallSites": 2 },
+	"grandTotal": { "total": 10, "counts": { "reflect": 1 } },
+	"packages": {
+		"ex/a": { "path": "ex/a", "depth": 0, "functionCount": 3 },
+		"fmt":  { "path": "fmt",  "depth": 1, "functionCount": 5 }
+	}
+}`
+
+func testEvaluator(t *testing.T) *evaluator {
+	t.Helper()
+	var root map[string]any
+	if err := json.Unmarshal([]byte(testReport), &root); err != nil {
+		t.Fatal(err)
+	}
+	return &evaluator{root: root}
+}
+
+func evalString(t *testing.T, ev *evaluator, src string) (any, error) {
+	t.Helper()
+	x, err := parser.ParseExpr(src)
+	if err != nil {
+		t.Fatalf("parse %q: %v", src, err)
+	}
+	return ev.eval(x)
+}
+
+func TestEvalPrecedence(t *testing.T) {
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3", 9.0},
+		{"10 - 4 - 3", 3.0},
+		{"8 / 4 / 2", 1.0},
+		{"-2 * 3 + 10 % 4", -4.0},
+		{"1 + 2 < 2 * 2", true},
+		{"1 < 2 && 2 < 1 || true", true},
+		{"true || true && false", true},
+		{"!(1 > 2) && indirect.funcVarCallSites / grandTotal.total < 0.5", true},
+		{"len(pkgs()) + sum(pkgs(), \"functionCount\") * 2", 18.0},
+		// && and || do not evaluate the side they do not need
+		{"false && nope > 0", false},
+		{"true || 1 / 0 > 0", true},
+	}
+	ev := testEvaluator(t)
+	for _, tt := range tests {
+		got, err := evalString(t, ev, tt.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{"unknown metric", "nope > 0", `unknown field "nope"`},
+		{"unknown nested metric", "indirect.nope > 0", `unknown field "nope"`},
+		{"field of a number", "indirect.funcVarCallSites.x", `.x on number 2`},
+		{"division by zero", "grandTotal.total / 0", `division by zero in grandTotal.total / 0`},
+		{"remainder by zero", `2 % grandTotal.counts["none"]`, `division by zero in 2 % grandTotal.counts["none"]`},
+		{"zero divisor computed", "1 / (2 - 2) < 1", `division by zero in 1 / (2 - 2)`},
+		{"mixed operands", `"a" < 1`, `< between "a" and number 1`},
+		{"negated string", `!"a"`, `! on "a"`},
+		{"list out of range", `pkgs()[2]`, `index 2 out of range [0,2)`},
+		{"unknown function", "avg(pkgs())", `unknown function avg`},
+		{"bad arity", "len(1, 2)", `len takes one argument`},
+	}
+	ev := testEvaluator(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := evalString(t, ev, tt.expr)
+			if err == nil {
+				t.Fatalf("%s: expected error %q", tt.expr, tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("%s: error %q, want %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalAbsentKeyIsZero(t *testing.T) {
+	got, err := evalString(t, testEvaluator(t), `grandTotal.counts["none"] + grandTotal.counts["reflect"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 1.0 {
+		t.Errorf("got %v, want 1", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		expr    string
+		outcome Outcome
+		value   string
+		err     string
+	}{
+		{"indirect.funcVarCallSites / grandTotal.total < 0.5", OutcomePass, "0.2 < 0.5", ""},
+		{"(indirect.funcVarCallSites > 5)", OutcomeFail, "2 > 5", ""},
+		{"len(depth(pkgs(), 1)) == 1 && true", OutcomePass, "true", ""},
+		{"indirect.funcVarCallSites + 1", OutcomeError, "", "expression is number 3, not a boolean"},
+		{"nope < 1", OutcomeError, "", `unknown field "nope"`},
+	}
+	ev := testEvaluator(t)
+	for _, tt := range tests {
+		x, err := parser.ParseExpr(tt.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.expr, err)
+		}
+		r := ev.run(&Budget{Name: tt.expr, Expr: tt.expr, expr: x})
+		if r.Outcome != tt.outcome || r.Value != tt.value || r.Error != tt.err {
+			t.Errorf("%s: got (%s, %q, %q), want (%s, %q, %q)",
+				tt.expr, r.Outcome, r.Value, r.Error, tt.outcome, tt.value, tt.err)
+		}
+	}
+}
+
+func TestLoadMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget string
+		want   string // after "<path>: "
+	}{
+		{"dangling operator", `{"name": "share", "expr": "1 +"}`, `share: 1:4: expected operand, found 'EOF'`},
+		{"unclosed paren", `{"expr": "(1 < 2"}`, `(1 < 2: 1:7: expected ')', found newline`},
+		{"trailing paren", `{"expr": "1 < 2)"}`, `1 < 2): 1:6: expected 'EOF', found ')'`},
+		{"two operands", `{"expr": "a b"}`, `a b: 1:3: expected 'EOF', found b`},
+		{"empty", `{"name": "none"}`, `budget 1 has no expression`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "budget.json")
+			data := `{"budgets": [` + tt.budget + `]}`
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if err == nil {
+				t.Fatalf("expected error %q", tt.want)
+			}
+			if want := path + ": " + tt.want; err.Error() != want {
+				t.Errorf("error %q, want %q", err, want)
+			}
+		})
+	}
+}
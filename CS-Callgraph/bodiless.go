@@ -0,0 +1,171 @@
+package cs_callgraph
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+/* ============================================================================
+ * BodyKind
+ * ----------------------------------------------------------------------------
+ * Classifies where a function's body lives. Only functions that were due to
+ * be scanned (in scope and within depth) are classified; a bodiless node is
+ * a point past which the graph is blind.
+ *
+ *   BodySource    ordinary Go body, scanned for edges
+ *   BodyAsm       declared in Go, implemented in a .s file of the package
+ *   BodyCgo       cgo trampoline or stub generated by cgo (_Cfunc_, _cgo...)
+ *   BodyLinkname  body pulled from another package via //go:linkname
+ *   BodyExternal  no source position, or origin could not be determined
+ * ============================================================================
+ */
+type BodyKind int
+
+const (
+    BodySource BodyKind = iota
+    BodyAsm
+    BodyCgo
+    BodyLinkname
+    BodyExternal
+)
+
+func (k BodyKind) String() string {
+    switch k {
+    case BodySource:   return "source"
+    case BodyAsm:      return "asm"
+    case BodyCgo:      return "cgo"
+    case BodyLinkname: return "linkname"
+    case BodyExternal: return "external"
+    default:           return "unknown"
+    }
+}
+
+/* ============================================================================
+ * bodyClassifier
+ * ----------------------------------------------------------------------------
+ * Classifies bodiless functions for one graph build. Source directories and
+ * files are inspected at most once each.
+ *
+ *   asmDirs    directory → has at least one .s file
+ *   linknames  file → local names carrying a //go:linkname directive
+ * ============================================================================
+ */
+type bodyClassifier struct {
+    asmDirs   map[string]bool
+    linknames map[string]map[string]bool
+}
+
+func newBodyClassifier() *bodyClassifier {
+    return &bodyClassifier{
+        asmDirs:   make(map[string]bool),
+        linknames: make(map[string]map[string]bool),
+    }
+}
+
+/* ============================================================================
+ * classify
+ * ----------------------------------------------------------------------------
+ * Returns the BodyKind of fn. Checked in order:
+ *
+ *   1. fn has blocks                      → BodySource
+ *   2. cgo naming or package runtime/cgo  → BodyCgo
+ *   3. no source position                 → BodyExternal
+ *   4. declared in _cgo_gotypes.go        → BodyCgo
+ *   5. //go:linkname fn in its file       → BodyLinkname
+ *   6. .s file next to its file           → BodyAsm
+ *   7. otherwise                          → BodyExternal
+ *
+ * Positions are taken without //line adjustment so cgo-generated files
+ * are inspected, not the user file they map back to.
+ * ============================================================================
+ */
+func (bc *bodyClassifier) classify(fn *ssa.Function) BodyKind {
+    if len(fn.Blocks) > 0 {
+        return BodySource
+    }
+    if isCgoName(fn.Name()) {
+        return BodyCgo
+    }
+    if pkg := EffectivePkg(fn); pkg != nil && pkg.Pkg != nil && pkg.Pkg.Path() == "runtime/cgo" {
+        return BodyCgo
+    }
+    if !fn.Pos().IsValid() {
+        return BodyExternal
+    }
+
+    file := fn.Prog.Fset.PositionFor(fn.Pos(), false).Filename
+    if file == "" {
+        return BodyExternal
+    }
+    if filepath.Base(file) == "_cgo_gotypes.go" {
+        return BodyCgo
+    }
+    if bc.linknamed(file)[fn.Name()] {
+        return BodyLinkname
+    }
+    if bc.hasAsm(filepath.Dir(file)) {
+        return BodyAsm
+    }
+    return BodyExternal
+}
+
+func isCgoName(name string) bool {
+    return strings.HasPrefix(name, "_Cfunc_") ||
+        strings.HasPrefix(name, "_C2func_") ||
+        strings.HasPrefix(name, "_Cgo_") ||
+        strings.HasPrefix(name, "_cgo")
+}
+
+/* ============================================================================
+ * hasAsm / linknamed
+ * ----------------------------------------------------------------------------
+ * hasAsm reports whether dir contains an assembly (.s) file. linknamed
+ * returns the local names that file declares with //go:linkname. Unreadable
+ * paths yield false / an empty set.
+ * ============================================================================
+ */
+func (bc *bodyClassifier) hasAsm(dir string) bool {
+    if has, ok := bc.asmDirs[dir]; ok {
+        return has
+    }
+    has := false
+    entries, _ := os.ReadDir(dir)
+    for _, e := range entries {
+        if !e.IsDir() && strings.HasSuffix(e.Name(), ".s") {
+            has = true
+            break
+        }
+    }
+    bc.asmDirs[dir] = has
+    return has
+}
+
+func (bc *bodyClassifier) linknamed(file string) map[string]bool {
+    if names, ok := bc.linknames[file]; ok {
+        return names
+    }
+    names := make(map[string]bool)
+    bc.linknames[file] = names
+
+    f, err := os.Open(file)
+    if err != nil {
+        return names
+    }
+    defer f.Close()
+
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if !strings.HasPrefix(line, "//go:linkname ") {
+            continue
+        }
+        if fields := strings.Fields(line); len(fields) >= 2 {
+            names[fields[1]] = true
+        }
+    }
+    return names
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"go/token"
 	"go/types"
+	"sort"
+	"strings"
 
 	"golang.org/x/tools/go/ssa"
 )
@@ -26,6 +28,10 @@ const (
     DeferEdge
     PanicEdge
     InterfaceEdge
+    DispatchEdge
+    EntryEdge
+    ReflectEdge
+    InstanceEdge
 )
 
 /* ============================================================================
@@ -44,6 +50,42 @@ func (k EdgeKind) String() string {
     case DeferEdge:     return "defer"
     case InterfaceEdge: return "interface"
     case PanicEdge:     return "panic"
+    case DispatchEdge:  return "dispatch"
+    case EntryEdge:     return "entry"
+    case ReflectEdge:   return "reflect"
+    case InstanceEdge:  return "instance"
+    default:            return "unknown"
+    }
+}
+
+/* ============================================================================
+ * Provenance
+ * ----------------------------------------------------------------------------
+ * Records which analysis produced an edge, so that graphs built with
+ * different resolution modes can be compared edge by edge.
+ *
+ *   ProvSyntactic  read directly off the instruction (static callee,
+ *                  literal function value, interface invoke)
+ *   ProvCHA        interface dispatch by class-hierarchy analysis
+ *   ProvRTA        interface dispatch by rapid-type analysis
+ *   ProvVTA        function-value call resolved by variable-type analysis
+ * ============================================================================
+ */
+type Provenance int
+
+const (
+    ProvSyntactic Provenance = iota
+    ProvCHA
+    ProvRTA
+    ProvVTA
+)
+
+func (p Provenance) String() string {
+    switch p {
+    case ProvSyntactic: return "syntactic"
+    case ProvCHA:       return "cha"
+    case ProvRTA:       return "rta"
+    case ProvVTA:       return "vta"
     default:            return "unknown"
     }
 }
@@ -60,6 +102,12 @@ type Node struct {
     ID   int
     In   []*Edge
     Out  []*Edge
+    Body BodyKind // Where the body lives; BodySource unless bodiless
+    Sink bool     // Matches a sink pattern (see MarkSinks)
+
+    // Context-expanded graphs only (see ExpandContexts)
+    Context []ssa.CallInstruction // Last k call sites, outermost first
+    Base    *Node                 // Collapsed node this context refines
 }
 
 type Graph struct {
@@ -67,6 +115,13 @@ type Graph struct {
     Nodes     map[*ssa.Function]*Node // All nodes indexed by SSA function
     PanicNode *Node                   // Single global sentinel sink
     IfaceNodes map[*types.Func]*Node
+
+    // Context-expanded graphs only (see ExpandContexts). Nodes and
+    // IfaceNodes then hold each function's empty-context node.
+    K        int     // Call-site context depth, 0 = collapsed
+    Contexts []*Node // Every (function, context) node
+
+    Std STDLib // Standard library packages of the analysis (see LoadSTDLib)
 }
 
 type Edge struct {
@@ -74,6 +129,7 @@ type Edge struct {
     Sites  []ssa.Instruction // Changed from 'Site ssa.Instruction'
     Callee *Node
     Kind   EdgeKind
+    Prov   Provenance        // Analysis that produced the edge
 }
 
 
@@ -87,6 +143,7 @@ type edgeKey struct {
     from *Node
     to   *Node
     kind EdgeKind
+    prov Provenance
 }
 
 /* ============================================================================
@@ -123,6 +180,51 @@ func InitGraph(root *ssa.Function) *Graph {
     return g
 }
 
+/* ============================================================================
+ * FunctionNodes / InterfaceNodes
+ * ----------------------------------------------------------------------------
+ * Return the function and interface method nodes of the graph, sorted by ID.
+ * For a context-expanded graph these are the (function, context) nodes, so
+ * consumers iterating them show the expanded view; for a collapsed graph
+ * they are the values of Nodes / IfaceNodes.
+ * ============================================================================
+ */
+func (g *Graph) FunctionNodes() []*Node {
+    var result []*Node
+    if g.K > 0 {
+        for _, n := range g.Contexts {
+            if n.IfaceMethod == nil {
+                result = append(result, n)
+            }
+        }
+    } else {
+        result = make([]*Node, 0, len(g.Nodes))
+        for _, n := range g.Nodes {
+            result = append(result, n)
+        }
+    }
+    sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+    return result
+}
+
+func (g *Graph) InterfaceNodes() []*Node {
+    var result []*Node
+    if g.K > 0 {
+        for _, n := range g.Contexts {
+            if n.IfaceMethod != nil {
+                result = append(result, n)
+            }
+        }
+    } else {
+        result = make([]*Node, 0, len(g.IfaceNodes))
+        for _, n := range g.IfaceNodes {
+            result = append(result, n)
+        }
+    }
+    sort.Slice(result, func(i, j int) bool { return result[i].ID > result[j].ID })
+    return result
+}
+
 /* ============================================================================
  * GenNode
  * ----------------------------------------------------------------------------
@@ -141,10 +243,51 @@ func (g *Graph) GenNode(fn *ssa.Function) *Node {
     return n
 }
 
+/* ============================================================================
+ * AttachRoot
+ * ----------------------------------------------------------------------------
+ * Fans the synthetic Root out to the given entry points (mains, package
+ * inits, explicit roots) with one EntryEdge each. Entries without a node in
+ * the graph are skipped.
+ * ============================================================================
+ */
+func (g *Graph) AttachRoot(entries []*ssa.Function) {
+    linked := map[*Node]bool{}
+    for _, e := range g.Root.Out {
+        linked[e.Callee] = true
+    }
+    for _, fn := range entries {
+        n, ok := g.Nodes[fn]
+        if !ok || fn == nil || linked[n] {
+            continue
+        }
+        linked[n] = true
+        GenEdge(g.Root, nil, n, EntryEdge)
+    }
+}
+
+/* ============================================================================
+ * Entries
+ * ----------------------------------------------------------------------------
+ * Returns the entry point nodes the Root fans out to, in attachment order.
+ * ============================================================================
+ */
+func (g *Graph) Entries() []*Node {
+    var result []*Node
+    for _, e := range g.Root.Out {
+        if e.Kind == EntryEdge {
+            result = append(result, e.Callee)
+        }
+    }
+    return result
+}
+
 /* ============================================================================
  * GenEdge
  * ----------------------------------------------------------------------------
  * Creates a new edge between two nodes and registers it in their in/out lists.
+ * A nil site produces a synthetic edge with no call sites (e.g. interface
+ * method → concrete implementation).
  * ============================================================================
  */
 func GenEdge(
@@ -155,10 +298,12 @@ func GenEdge(
 ) *Edge {
     e := &Edge{
         Caller: caller,
-        Sites:  []ssa.Instruction{site},
         Callee: callee,
         Kind:   kind,
     }
+    if site != nil {
+        e.Sites = []ssa.Instruction{site}
+    }
     caller.Out = append(caller.Out, e)
     callee.In  = append(callee.In , e)
     return e
@@ -172,12 +317,53 @@ func GenEdge(
  */
 func (n *Node) String() string {
     if n.IfaceMethod != nil {
-        return fmt.Sprintf("iface%d:%s", n.ID, n.IfaceMethod.FullName())
+        return fmt.Sprintf("iface%d:%s%s", n.ID, n.IfaceMethod.FullName(), n.ContextString())
     }
     if n.Func == nil {
         return fmt.Sprintf("n%d:<root>", n.ID)
     }
-    return fmt.Sprintf("n%d:%s", n.ID, n.Func.String())
+    return fmt.Sprintf("n%d:%s%s", n.ID, n.Func.String(), n.ContextString())
+}
+
+/* ============================================================================
+ * ContextString
+ * ----------------------------------------------------------------------------
+ * Returns the node's call-site context as " @[file:line → file:line]", or ""
+ * for collapsed and empty-context nodes.
+ * ============================================================================
+ */
+func (n *Node) ContextString() string {
+    if len(n.Context) == 0 {
+        return ""
+    }
+    sites := make([]string, len(n.Context))
+    for i, site := range n.Context {
+        sites[i] = sitePos(site)
+    }
+    return " @[" + strings.Join(sites, " → ") + "]"
+}
+
+/* ============================================================================
+ * InnermostSite
+ * ----------------------------------------------------------------------------
+ * Returns "file:line" of the most recent call site in the node's context, or
+ * "" if the node has no context. Used as a compact label suffix.
+ * ============================================================================
+ */
+func (n *Node) InnermostSite() string {
+    if len(n.Context) == 0 {
+        return ""
+    }
+    return sitePos(n.Context[len(n.Context)-1])
+}
+
+func sitePos(site ssa.Instruction) string {
+    pos  := site.Parent().Prog.Fset.Position(site.Pos())
+    file := pos.Filename
+    if i := strings.LastIndex(file, "/"); i >= 0 {
+        file = file[i+1:]
+    }
+    return fmt.Sprintf("%s:%d", file, pos.Line)
 }
 
 /* ============================================================================
@@ -198,15 +384,22 @@ func (e *Edge) String() string {
 /* ============================================================================
  * Description
  * ----------------------------------------------------------------------------
- * Returns a text description of the call site, identifying special dispatch.
+ * Returns a text description of the call sites, one per line, each prefixed
+ * with its "file:line" and identifying special dispatch.
  * ============================================================================
  */
 func (e *Edge) Description() string {
     if len(e.Sites) == 0 {
+        if e.Prov != ProvSyntactic {
+            return "synthetic edge [" + e.Prov.String() + "]"
+        }
         return "synthetic edge"
     }
 
     var res string
+    if e.Prov != ProvSyntactic {
+        res = "[" + e.Prov.String() + "] "
+    }
     for i, site := range e.Sites {
         var prefix string
         switch site.(type) {
@@ -215,6 +408,9 @@ func (e *Edge) Description() string {
         default:         prefix = ""
         }
 
+        if site.Pos().IsValid() {
+            res += sitePos(site) + ": "
+        }
         res += prefix + site.String()
         if i < len(e.Sites)-1 {
             res += "\n"
@@ -241,4 +437,22 @@ func (e *Edge) Pos() token.Pos {
     }
 
     return token.NoPos
+}
+
+/* ============================================================================
+ * Positions
+ * ----------------------------------------------------------------------------
+ * Resolves every site of the edge through the program's FileSet, in site
+ * order. Sites without a source position (synthetic code) are skipped.
+ * ============================================================================
+ */
+func (e *Edge) Positions() []token.Position {
+    var result []token.Position
+    for _, site := range e.Sites {
+        if site == nil || !site.Pos().IsValid() {
+            continue
+        }
+        result = append(result, site.Parent().Prog.Fset.Position(site.Pos()))
+    }
+    return result
 }
\ No newline at end of file
@@ -2,9 +2,11 @@ package cs_callgraph
 
 import (
 	"go/types"
-	"sync"
 
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
 	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
 )
 
 /* ============================================================================
@@ -20,54 +22,52 @@ import (
  *   2. fn.Origin()     - generic instantiation → template function
  *   3. fn.Parent()     - closure / anonymous function → enclosing function
  *
- * Results are memoized in EffectivePkgCache to avoid redundant traversals
- * across repeated lookups of the same function. Returns nil if no package
- * can be structurally determined.
+ * Each step is a field read and the chain is at most a few links long, so
+ * nothing is memoized and no state outlives the call. Returns nil if no
+ * package can be structurally determined.
  * ============================================================================
  */
-var EffectivePkgCache sync.Map // map[*ssa.Function]*ssa.Package
-
 func EffectivePkg(fn *ssa.Function) *ssa.Package {
     if fn == nil {
         return nil
     }
-    if cached, ok := EffectivePkgCache.Load(fn); ok {
-        return cached.(*ssa.Package)
-    }
-
-    var result *ssa.Package
-
     if fn.Pkg != nil {
-        result = fn.Pkg
-    } else if origin := fn.Origin(); origin != nil && origin != fn {
-        result = EffectivePkg(origin)
-    } else if parent := fn.Parent(); parent != nil {
-        result = EffectivePkg(parent)
+        return fn.Pkg
     }
-
-    EffectivePkgCache.Store(fn, result)
-    return result
+    if origin := fn.Origin(); origin != nil && origin != fn {
+        return EffectivePkg(origin)
+    }
+    if parent := fn.Parent(); parent != nil {
+        return EffectivePkg(parent)
+    }
+    return nil
 }
 /* ============================================================================
  * resolveServiceableFunc
  * ----------------------------------------------------------------------------
  * Resolves a function to its logical origin. If a function is a generic
  * instantiation or a synthetic wrapper, this returns the original template
- * or method where the package info resides.
+ * or method where the package info resides. Instantiations are folded onto
+ * their origin unless mode is GenericsInstances, which keeps those that have
+ * their own body (ssa.InstantiateGenerics).
  * ============================================================================
  */
-func resolveServiceableFunc(fn *ssa.Function) *ssa.Function {
+func resolveServiceableFunc(fn *ssa.Function, mode GenericsMode) *ssa.Function {
     if fn == nil {
         return nil
     }
-    if fn.Pkg != nil {
+    origin := fn.Origin()
+    if origin != nil && origin != fn && mode != GenericsInstances {
+        return resolveServiceableFunc(origin, mode)
+    }
+    if fn.Pkg != nil || isInstance(fn) {
         return fn
     }
-    if origin := fn.Origin(); origin != nil && origin != fn {
-        return resolveServiceableFunc(origin)
+    if origin != nil && origin != fn {
+        return resolveServiceableFunc(origin, mode)
     }
     if parent := fn.Parent(); parent != nil {
-        return resolveServiceableFunc(parent)
+        return resolveServiceableFunc(parent, mode)
     }
     return fn
 }
@@ -76,24 +76,24 @@ func resolveServiceableFunc(fn *ssa.Function) *ssa.Function {
  * ----------------------------------------------------------------------------
  * Determines if an ssa.Value is a function, closure, or a type-cast function.
  * Automatically resolves specialized generics or synthetic wrappers to 
- * their logical origins.
+ * their logical origins (see resolveServiceableFunc for mode).
  * ============================================================================
  */
-func isFuncValue(v ssa.Value) (*ssa.Function, bool) {
+func isFuncValue(v ssa.Value, mode GenericsMode) (*ssa.Function, bool) {
     switch v := v.(type) {
         case *ssa.Function:
-            return resolveServiceableFunc(v), true
+            return resolveServiceableFunc(v, mode), true
 
         case *ssa.MakeClosure:
             if fn, ok := v.Fn.(*ssa.Function); ok {
-                return resolveServiceableFunc(fn), true
+                return resolveServiceableFunc(fn, mode), true
             }
 
         case *ssa.ChangeType:
-            return isFuncValue(v.X)
+            return isFuncValue(v.X, mode)
 
         case *ssa.MakeInterface:
-            return isFuncValue(v.X)
+            return isFuncValue(v.X, mode)
     }
     return nil, false
 }
@@ -104,18 +104,18 @@ func isFuncValue(v ssa.Value) (*ssa.Function, bool) {
  * Scans an instruction for all possible edges (calls, goroutines, returns).
  * ============================================================================
  */
-func extractEdges(cg *Graph, instr ssa.Instruction) []nodeKind {
+func extractEdges(cg *Graph, instr ssa.Instruction, mode GenericsMode) []nodeKind {
     switch i := instr.(type) {
     case *ssa.Go:
         call := i.Common()
         if callee := call.StaticCallee(); callee != nil {
-            return []nodeKind{{cg.GenNode(resolveServiceableFunc(callee)), GoEdge}}
+            return []nodeKind{{cg.GenNode(resolveServiceableFunc(callee, mode)), GoEdge}}
         }
 
     case *ssa.Defer:
         call := i.Common()
         if callee := call.StaticCallee(); callee != nil {
-            return []nodeKind{{cg.GenNode(resolveServiceableFunc(callee)), DeferEdge}}
+            return []nodeKind{{cg.GenNode(resolveServiceableFunc(callee, mode)), DeferEdge}}
         }
 
     case ssa.CallInstruction:
@@ -123,10 +123,10 @@ func extractEdges(cg *Graph, instr ssa.Instruction) []nodeKind {
         var results []nodeKind
 
         if callee := call.StaticCallee(); callee != nil {
-            target := resolveServiceableFunc(callee)
+            target := resolveServiceableFunc(callee, mode)
             results = append(results, nodeKind{cg.GenNode(target), CallEdge})
         } else {
-            if fnVal, ok := isFuncValue(call.Value); ok {
+            if fnVal, ok := isFuncValue(call.Value, mode); ok {
                 results = append(results, nodeKind{cg.GenNode(fnVal), CallEdge})
             } else if call.IsInvoke() {
                 ifaceNode := cg.GenIfaceNode(call.Method)
@@ -135,14 +135,14 @@ func extractEdges(cg *Graph, instr ssa.Instruction) []nodeKind {
                 }
             } else if call.Method != nil {
                 if fn := i.Parent().Prog.FuncValue(call.Method); fn != nil {
-                    results = append(results, nodeKind{cg.GenNode(resolveServiceableFunc(fn)), CallEdge})
+                    results = append(results, nodeKind{cg.GenNode(resolveServiceableFunc(fn, mode)), CallEdge})
                 }
             }
         }
 
         // Functional arguments (callbacks)
         for _, arg := range call.Args {
-            if fnVal, ok := isFuncValue(arg); ok {
+            if fnVal, ok := isFuncValue(arg, mode); ok {
                 results = append(results, nodeKind{cg.GenNode(fnVal), AssignEdge})
             }
         }
@@ -151,19 +151,19 @@ func extractEdges(cg *Graph, instr ssa.Instruction) []nodeKind {
     case *ssa.Return:
         var results []nodeKind
         for _, val := range i.Results {
-            if fnVal, ok := isFuncValue(val); ok {
+            if fnVal, ok := isFuncValue(val, mode); ok {
                 results = append(results, nodeKind{cg.GenNode(fnVal), AssignEdge})
             }
         }
         return results
 
     case *ssa.MapUpdate:
-        if fnVal, ok := isFuncValue(i.Value); ok {
+        if fnVal, ok := isFuncValue(i.Value, mode); ok {
             return []nodeKind{{cg.GenNode(fnVal), AssignEdge}}
         }
         
     case *ssa.Store:
-        if fnVal, ok := isFuncValue(i.Val); ok {
+        if fnVal, ok := isFuncValue(i.Val, mode); ok {
             return []nodeKind{{cg.GenNode(fnVal), AssignEdge}}
         }
 
@@ -171,12 +171,25 @@ func extractEdges(cg *Graph, instr ssa.Instruction) []nodeKind {
         return []nodeKind{{cg.PanicNode, PanicEdge}}
 
     case *ssa.Send:
-        if fnVal, ok := isFuncValue(i.X); ok {
+        if fnVal, ok := isFuncValue(i.X, mode); ok {
             return []nodeKind{{cg.GenNode(fnVal), SendEdge}}
         }
+
+    case *ssa.Select:
+        var results []nodeKind
+        for _, st := range i.States {
+            if st.Dir != types.SendOnly {
+                continue
+            }
+            if fnVal, ok := isFuncValue(st.Send, mode); ok {
+                results = append(results, nodeKind{cg.GenNode(fnVal), SendEdge})
+            }
+        }
+        return results
+
     case *ssa.TypeAssert:
         // Detects: f := val.(func())
-        if fnVal, ok := isFuncValue(i.X); ok {
+        if fnVal, ok := isFuncValue(i.X, mode); ok {
             return []nodeKind{{cg.GenNode(fnVal), AssignEdge}}
         }
     }
@@ -188,17 +201,35 @@ func extractEdges(cg *Graph, instr ssa.Instruction) []nodeKind {
  * BuildExtendedCallGraph2
  * ----------------------------------------------------------------------------
  * Entry point for building the graph by visiting all reachable functions.
+ * ifaceMode selects how interface method nodes are linked to their concrete
+ * implementations (see IfaceMode); funcMode selects how calls through
+ * function values are resolved (see FuncValueMode); genMode whether generic
+ * instantiations get nodes of their own (see GenericsMode). Calls made through
+ * reflect.Value are linked to their possible targets with ReflectEdges (see
+ * resolveReflectCalls), and functions received from a channel and called
+ * get ReceiveEdges to what was sent (see resolveChannelFlow). extraRoots
+ * are visited in addition to package-level functions - e.g. exported
+ * methods in library mode, which are not package members and would
+ * otherwise only appear once something calls them.
  * ============================================================================
  */
 func BuildExtendedCallGraph2(
-    prog     *ssa.Program,
-    maxDepth int,
-    depthMap map[string]int,
-    skipPkg  map[string]struct{},
+    prog       *ssa.Program,
+    maxDepth   int,
+    depthMap   map[string]int,
+    skipPkg    map[string]struct{},
+    ifaceMode  IfaceMode,
+    funcMode   FuncValueMode,
+    genMode    GenericsMode,
+    extraRoots []*ssa.Function,
 ) *Graph {
     cg            := InitGraph(nil)
     seen          := map[*ssa.Function]bool{}
     existingEdges := map[edgeKey]*Edge{}
+    reflection    := reflectState{genMode: genMode}
+    channels      := chanFlow{genMode: genMode}
+    var liveTypes typeutil.Map // RTA: types converted to an interface
+    bodies := newBodyClassifier()
 
     /* -------------------------------------------------------
      * pkgStatus centralises the two questions asked in both
@@ -255,6 +286,9 @@ func BuildExtendedCallGraph2(
      *   4. pkgStatus.withinDepth - stubs deep nodes (GenNode
      *                             only, body not scanned)
      *
+     * Scanned functions without blocks are classified (asm,
+     * cgo, linkname, external) into Node.Body.
+     *
      * For each in-scope function, all outgoing edges are
      * extracted and deduplicated via existingEdges before
      * recursing into each callee.
@@ -277,14 +311,27 @@ func BuildExtendedCallGraph2(
             cg.GenNode(fn)
             return
         }
+        if genMode == GenericsInstances && isGenericBody(fn) {
+            cg.GenNode(fn) // body is represented by its instances
+            return
+        }
 
         seen[fn] = true
         callerNode := cg.GenNode(fn)
+        if genMode == GenericsInstances {
+            linkInstance(cg, callerNode, existingEdges)
+        }
+        callerNode.Body = bodies.classify(fn)
 
         for _, block := range fn.Blocks {
             for _, instr := range block.Instrs {
-                for _, e := range extractEdges(cg, instr) {
-                    key := edgeKey{from: callerNode, to: e.node, kind: e.kind}
+                if mi, ok := instr.(*ssa.MakeInterface); ok {
+                    liveTypes.Set(mi.X.Type(), true)
+                }
+                reflection.observe(callerNode, instr)
+                channels.observe(callerNode, instr)
+                for _, e := range extractEdges(cg, instr, genMode) {
+                    key := edgeKey{from: callerNode, to: e.node, kind: e.kind, prov: ProvSyntactic}
                     if edge, exists := existingEdges[key]; exists {
                         edge.Sites = append(edge.Sites, instr)
                     } else {
@@ -312,6 +359,130 @@ func BuildExtendedCallGraph2(
             }
         }
     }
+    for _, fn := range extraRoots {
+        visit(resolveServiceableFunc(fn, genMode))
+    }
+
+    /* -------------------------------------------------------
+     * Whole-program resolution passes. Each may visit new
+     * functions whose bodies feed the other, so alternate
+     * until a round scans nothing new. The CHA graph that seeds
+     * VTA only depends on prog, so it is built once.
+     * ------------------------------------------------------- */
+    var chaGraph *callgraph.Graph
+    if funcMode == FuncValVTA {
+        chaGraph = cha.CallGraph(prog)
+    }
+    for {
+        scannedBefore := len(seen)
+
+        if ifaceMode != IfaceNone {
+            resolveIfaceDispatch(
+                prog, cg, ifaceMode, genMode, &liveTypes, existingEdges, pkgStatus, visit,
+            )
+        }
+        if funcMode == FuncValVTA {
+            for _, target := range resolveFuncValuesVTA(
+                cg, seen, chaGraph, existingEdges, pkgStatus, genMode,
+            ) {
+                visit(target)
+            }
+        }
+        for _, target := range resolveReflectCalls(prog, cg, &reflection, existingEdges, pkgStatus) {
+            visit(target)
+        }
+        for _, target := range resolveChannelFlow(cg, &channels, existingEdges) {
+            visit(target)
+        }
+
+        if len(seen) == scannedBefore {
+            break
+        }
+    }
 
     return cg
+}
+
+/* ============================================================================
+ * resolveIfaceDispatch
+ * ----------------------------------------------------------------------------
+ * Adds a DispatchEdge from every interface method node to each concrete
+ * method that may implement it, then visits those methods.
+ *
+ * Visiting an implementation can create new interface nodes and, under RTA,
+ * new live types, so the pass repeats until no new edge is added.
+ *
+ * Implementations in unknown (out of scope or skipped) packages are dropped,
+ * matching what visit does for ordinary callees.
+ * ============================================================================
+ */
+func resolveIfaceDispatch(
+    prog          *ssa.Program,
+    cg            *Graph,
+    mode          IfaceMode,
+    genMode       GenericsMode,
+    liveTypes     *typeutil.Map,
+    existingEdges map[edgeKey]*Edge,
+    pkgStatus     func(string) (bool, bool),
+    visit         func(*ssa.Function),
+) {
+    index := newMethodIndex(prog, genMode)
+    prov  := ProvCHA
+    if mode == IfaceRTA {
+        prov = ProvRTA
+    }
+
+    if mode == IfaceCHA {
+        for _, pkg := range prog.AllPackages() {
+            if pkg.Pkg == nil {
+                continue
+            }
+            if known, _ := pkgStatus(pkg.Pkg.Path()); !known {
+                continue
+            }
+            for _, t := range declaredTypes(pkg) {
+                index.add(t)
+            }
+        }
+    }
+
+    for {
+        if mode == IfaceRTA {
+            liveTypes.Iterate(func(t types.Type, _ any) {
+                index.add(t)
+            })
+        }
+
+        ifaceNodes := make([]*Node, 0, len(cg.IfaceNodes))
+        for _, n := range cg.IfaceNodes {
+            ifaceNodes = append(ifaceNodes, n)
+        }
+
+        added := false
+        for _, n := range ifaceNodes {
+            for _, impl := range index.implementations(n.IfaceMethod) {
+                pkg := EffectivePkg(impl)
+                if pkg == nil || pkg.Pkg == nil {
+                    continue
+                }
+                if known, _ := pkgStatus(pkg.Pkg.Path()); !known {
+                    continue
+                }
+
+                callee := cg.GenNode(impl)
+                key    := edgeKey{from: n, to: callee, kind: DispatchEdge, prov: prov}
+                if _, exists := existingEdges[key]; exists {
+                    continue
+                }
+                edge := GenEdge(n, nil, callee, DispatchEdge)
+                edge.Prov = prov
+                existingEdges[key] = edge
+                added = true
+                visit(impl)
+            }
+        }
+        if !added {
+            return
+        }
+    }
 }
\ No newline at end of file
@@ -0,0 +1,297 @@
+package cs_callgraph
+
+import (
+	"fmt"
+	"math"
+)
+
+/* ============================================================================
+ * Centrality
+ * ----------------------------------------------------------------------------
+ * Structural importance of one node within the measured subgraph.
+ *
+ *   FanIn           distinct callers
+ *   FanOut          distinct callees
+ *   CallerPackages  distinct packages the callers belong to
+ *   Betweenness     number of shortest caller → callee paths between other
+ *                   nodes that pass through this one (Brandes; paths split
+ *                   evenly between equally short alternatives)
+ *   PageRank        stationary probability of a random walk along calls
+ *                   (damping 0.85); sums to 1 over the subgraph
+ * ============================================================================
+ */
+type Centrality struct {
+    FanIn          int
+    FanOut         int
+    CallerPackages int
+    Betweenness    float64
+    PageRank       float64
+}
+
+/* ============================================================================
+ * CentralityMetric
+ * ----------------------------------------------------------------------------
+ * Selects one Centrality field, e.g. to size nodes in the DOT output.
+ *
+ *   MetricNone            no metric
+ *   MetricFanIn           FanIn
+ *   MetricFanOut          FanOut
+ *   MetricCallerPackages  CallerPackages
+ *   MetricBetweenness     Betweenness
+ *   MetricPageRank        PageRank
+ * ============================================================================
+ */
+type CentralityMetric int
+
+const (
+    MetricNone CentralityMetric = iota
+    MetricFanIn
+    MetricFanOut
+    MetricCallerPackages
+    MetricBetweenness
+    MetricPageRank
+)
+
+func (m CentralityMetric) String() string {
+    switch m {
+    case MetricNone:           return "none"
+    case MetricFanIn:          return "fanin"
+    case MetricFanOut:         return "fanout"
+    case MetricCallerPackages: return "callerpkgs"
+    case MetricBetweenness:    return "betweenness"
+    case MetricPageRank:       return "pagerank"
+    default:                   return "unknown"
+    }
+}
+
+/* ============================================================================
+ * ParseCentralityMetric
+ * ----------------------------------------------------------------------------
+ * Converts a flag value ("none", "fanin", "fanout", "callerpkgs",
+ * "betweenness", "pagerank") into a CentralityMetric.
+ * ============================================================================
+ */
+func ParseCentralityMetric(s string) (CentralityMetric, error) {
+    switch s {
+    case "none":        return MetricNone, nil
+    case "fanin":       return MetricFanIn, nil
+    case "fanout":      return MetricFanOut, nil
+    case "callerpkgs":  return MetricCallerPackages, nil
+    case "betweenness": return MetricBetweenness, nil
+    case "pagerank":    return MetricPageRank, nil
+    }
+    return MetricNone, fmt.Errorf(
+        "unknown metric %q (want none, fanin, fanout, callerpkgs, betweenness or pagerank)", s,
+    )
+}
+
+/* ============================================================================
+ * Value
+ * ----------------------------------------------------------------------------
+ * Returns the field of c selected by m (0 for MetricNone).
+ * ============================================================================
+ */
+func (c *Centrality) Value(m CentralityMetric) float64 {
+    switch m {
+    case MetricFanIn:          return float64(c.FanIn)
+    case MetricFanOut:         return float64(c.FanOut)
+    case MetricCallerPackages: return float64(c.CallerPackages)
+    case MetricBetweenness:    return c.Betweenness
+    case MetricPageRank:       return c.PageRank
+    default:                   return 0
+    }
+}
+
+const (
+    pageRankDamping    = 0.85
+    pageRankIterations = 100
+    pageRankTolerance  = 1e-10
+)
+
+/* ============================================================================
+ * ComputeCentrality
+ * ----------------------------------------------------------------------------
+ * Computes Centrality for every function and interface method node of g
+ * that include accepts, over the subgraph of those nodes and the call
+ * edges between them (see isQueryEdge). Parallel edges between the same
+ * two nodes count once. Betweenness is O(V·E) - use include to keep the
+ * subgraph to the packages of interest.
+ * ============================================================================
+ */
+func ComputeCentrality(g *Graph, include func(*Node) bool) map[*Node]*Centrality {
+    var nodes []*Node
+    for _, n := range append(g.FunctionNodes(), g.InterfaceNodes()...) {
+        if (n.Func != nil || n.IfaceMethod != nil) && include(n) {
+            nodes = append(nodes, n)
+        }
+    }
+    index := make(map[*Node]int, len(nodes))
+    for i, n := range nodes {
+        index[n] = i
+    }
+
+    // Distinct successor / predecessor lists over the subgraph
+    succ := make([][]int, len(nodes))
+    pred := make([][]int, len(nodes))
+    for i, n := range nodes {
+        seen := make(map[int]bool)
+        for _, e := range n.Out {
+            j, ok := index[e.Callee]
+            if !ok || !isQueryEdge(e) || seen[j] {
+                continue
+            }
+            seen[j] = true
+            succ[i] = append(succ[i], j)
+            pred[j] = append(pred[j], i)
+        }
+    }
+
+    result := make(map[*Node]*Centrality, len(nodes))
+    for i, n := range nodes {
+        pkgs := make(map[string]bool)
+        for _, j := range pred[i] {
+            pkgs[NodePackage(nodes[j])] = true
+        }
+        result[n] = &Centrality{
+            FanIn:          len(pred[i]),
+            FanOut:         len(succ[i]),
+            CallerPackages: len(pkgs),
+        }
+    }
+
+    for i, b := range betweenness(succ) {
+        result[nodes[i]].Betweenness = b
+    }
+    for i, r := range pageRank(succ) {
+        result[nodes[i]].PageRank = r
+    }
+    return result
+}
+
+/* -------------------------------------------------------
+ * betweenness
+ * Brandes' algorithm for unweighted directed graphs.
+ * ------------------------------------------------------- */
+func betweenness(succ [][]int) []float64 {
+    n  := len(succ)
+    cb := make([]float64, n)
+
+    sigma := make([]float64, n)
+    dist  := make([]int, n)
+    delta := make([]float64, n)
+    preds := make([][]int, n)
+
+    for i := range dist {
+        dist[i] = -1
+    }
+    for s := 0; s < n; s++ {
+        sigma[s], dist[s] = 1, 0
+
+        order := []int{}
+        queue := []int{s}
+        for len(queue) > 0 {
+            v := queue[0]
+            queue = queue[1:]
+            order = append(order, v)
+            for _, w := range succ[v] {
+                if dist[w] < 0 {
+                    dist[w] = dist[v] + 1
+                    queue   = append(queue, w)
+                }
+                if dist[w] == dist[v]+1 {
+                    sigma[w] += sigma[v]
+                    preds[w]  = append(preds[w], v)
+                }
+            }
+        }
+
+        for i := len(order) - 1; i >= 0; i-- {
+            w := order[i]
+            for _, v := range preds[w] {
+                delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+            }
+            if w != s {
+                cb[w] += delta[w]
+            }
+        }
+
+        // Only the nodes this search reached need resetting
+        for _, v := range order {
+            sigma[v], dist[v], delta[v] = 0, -1, 0
+            preds[v] = preds[v][:0]
+        }
+    }
+    return cb
+}
+
+/* -------------------------------------------------------
+ * pageRank
+ * Power iteration; the rank of nodes without callees is
+ * spread evenly over all nodes.
+ * ------------------------------------------------------- */
+func pageRank(succ [][]int) []float64 {
+    n := len(succ)
+    if n == 0 {
+        return nil
+    }
+    rank := make([]float64, n)
+    next := make([]float64, n)
+    for i := range rank {
+        rank[i] = 1 / float64(n)
+    }
+
+    for range pageRankIterations {
+        dangling := 0.0
+        for i, out := range succ {
+            if len(out) == 0 {
+                dangling += rank[i]
+            }
+        }
+        base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+        for i := range next {
+            next[i] = base
+        }
+        for i, out := range succ {
+            if len(out) == 0 {
+                continue
+            }
+            share := pageRankDamping * rank[i] / float64(len(out))
+            for _, j := range out {
+                next[j] += share
+            }
+        }
+
+        diff := 0.0
+        for i := range rank {
+            diff += math.Abs(next[i] - rank[i])
+        }
+        rank, next = next, rank
+        if diff < pageRankTolerance {
+            break
+        }
+    }
+    return rank
+}
+
+/* ============================================================================
+ * NodePackage
+ * ----------------------------------------------------------------------------
+ * Returns the package path of a function or interface method node, "" for
+ * the root, the panic sink and functions without a package.
+ * ============================================================================
+ */
+func NodePackage(n *Node) string {
+    if n.IfaceMethod != nil {
+        if n.IfaceMethod.Pkg() == nil {
+            return ""
+        }
+        return n.IfaceMethod.Pkg().Path()
+    }
+    if n.Func == nil {
+        return ""
+    }
+    if pkg := EffectivePkg(n.Func); pkg != nil && pkg.Pkg != nil {
+        return pkg.Pkg.Path()
+    }
+    return ""
+}
@@ -0,0 +1,104 @@
+package cs_callgraph_test
+
+import (
+    "math"
+    "testing"
+
+    cs_callgraph "callstat/CS-Callgraph"
+    "callstat/CS-Callgraph/cgtest"
+)
+
+func includeAll(*cs_callgraph.Node) bool { return true }
+
+func assertClose(t *testing.T, what string, got, want float64) {
+    t.Helper()
+    if math.Abs(got-want) > 1e-8 {
+        t.Errorf("%s = %.10f, want %.10f", what, got, want)
+    }
+}
+
+/* -------------------------------------------------------
+ * Chain A -> B -> C
+ * Betweenness: only B sits between two others (A -> C).
+ * PageRank with C dangling, q = (1-d)/3 + d·r(C)/3:
+ *   r(A) = q, r(B) = q + d·q, r(C) = q + d·r(B)
+ * i.e. 1 : 1.85 : 2.5725, normalised by 5.4225.
+ * ------------------------------------------------------- */
+func TestCentralityChain(t *testing.T) {
+    g, n := cgtest.Build(t, "ex.A -> ex.B", "ex.B -> ex.C")
+    c := cs_callgraph.ComputeCentrality(g, includeAll)
+
+    assertClose(t, "betweenness(A)", c[n["ex.A"]].Betweenness, 0)
+    assertClose(t, "betweenness(B)", c[n["ex.B"]].Betweenness, 1)
+    assertClose(t, "betweenness(C)", c[n["ex.C"]].Betweenness, 0)
+
+    assertClose(t, "pagerank(A)", c[n["ex.A"]].PageRank, 1/5.4225)
+    assertClose(t, "pagerank(B)", c[n["ex.B"]].PageRank, 1.85/5.4225)
+    assertClose(t, "pagerank(C)", c[n["ex.C"]].PageRank, 2.5725/5.4225)
+}
+
+/* -------------------------------------------------------
+ * Star C1, C2 -> H -> L1, L2
+ * H is on all four Ci -> Lj shortest paths.
+ * PageRank with L1, L2 dangling, b = (1-d)/5 + d·2r(L)/5:
+ *   r(C) = b, r(H) = b + 2d·b, r(L) = b + d·r(H)/2
+ * i.e. 1 : 2.7 : 2.1475, normalised by 8.995.
+ * ------------------------------------------------------- */
+func TestCentralityStar(t *testing.T) {
+    g, n := cgtest.Build(t,
+        "ex/a.C1 -> ex.H", "ex/b.C2 -> ex.H",
+        "ex.H -> ex.L1", "ex.H -> ex.L2",
+    )
+    c := cs_callgraph.ComputeCentrality(g, includeAll)
+
+    hub := c[n["ex.H"]]
+    if hub.FanIn != 2 || hub.FanOut != 2 || hub.CallerPackages != 2 {
+        t.Errorf("hub fan-in/out/caller packages = %d/%d/%d, want 2/2/2",
+            hub.FanIn, hub.FanOut, hub.CallerPackages)
+    }
+    assertClose(t, "betweenness(H)", hub.Betweenness, 4)
+    for _, name := range []string{"ex/a.C1", "ex/b.C2", "ex.L1", "ex.L2"} {
+        assertClose(t, "betweenness("+name+")", c[n[name]].Betweenness, 0)
+    }
+
+    assertClose(t, "pagerank(C1)", c[n["ex/a.C1"]].PageRank, 1/8.995)
+    assertClose(t, "pagerank(H)", hub.PageRank, 2.7/8.995)
+    assertClose(t, "pagerank(L1)", c[n["ex.L1"]].PageRank, 2.1475/8.995)
+
+    sum := 0.0
+    for _, v := range c {
+        sum += v.PageRank
+    }
+    assertClose(t, "pagerank sum", sum, 1)
+}
+
+// Two equally short S -> T paths share the pair between their middles
+func TestBetweennessSplitsEqualPaths(t *testing.T) {
+    g, n := cgtest.Build(t, "ex.S -> ex.X", "ex.S -> ex.Y", "ex.X -> ex.T", "ex.Y -> ex.T")
+    c := cs_callgraph.ComputeCentrality(g, includeAll)
+
+    assertClose(t, "betweenness(X)", c[n["ex.X"]].Betweenness, 0.5)
+    assertClose(t, "betweenness(Y)", c[n["ex.Y"]].Betweenness, 0.5)
+}
+
+// Parallel edges count once; excluded nodes and panic edges are not measured
+func TestCentralitySubgraph(t *testing.T) {
+    g, n := cgtest.Build(t,
+        "ex.A -> ex.B", "ex.A -go-> ex.B", "ex.A -panic-> ex.C",
+        "ex.B -> ex/skip.D", "ex/skip.D -> ex.C",
+    )
+    c := cs_callgraph.ComputeCentrality(g, func(n *cs_callgraph.Node) bool { return cs_callgraph.NodePackage(n) != "ex/skip" })
+
+    if _, ok := c[n["ex/skip.D"]]; ok {
+        t.Errorf("excluded node was measured")
+    }
+    if a := c[n["ex.A"]]; a.FanOut != 1 {
+        t.Errorf("fan-out(A) = %d, want 1", a.FanOut)
+    }
+    if b := c[n["ex.B"]]; b.FanIn != 1 || b.FanOut != 0 {
+        t.Errorf("fan-in/out(B) = %d/%d, want 1/0", b.FanIn, b.FanOut)
+    }
+    if cc := c[n["ex.C"]]; cc.FanIn != 0 {
+        t.Errorf("fan-in(C) = %d, want 0", cc.FanIn)
+    }
+}
@@ -0,0 +1,118 @@
+// Package cgtest builds small call graphs of a known shape for the tests of
+// cs_callgraph and the packages built on it.
+package cgtest
+
+import (
+    "go/token"
+    "go/types"
+    "strings"
+    "testing"
+
+    cs_callgraph "callstat/CS-Callgraph"
+
+    "golang.org/x/tools/go/ssa"
+)
+
+/* ============================================================================
+ * Build
+ * ----------------------------------------------------------------------------
+ * Builds a collapsed Graph from edge specs, without loading a program:
+ *
+ *   "ex/a.F -> ex/b.G"          call edge
+ *   "ex/a.F -go-> ex/b.G"       edge of any kind, named as EdgeKind.String
+ *   "ex/a.F"                    node without edges
+ *
+ * Each function gets a real *ssa.Function in a package created from type
+ * information only, so QualifiedName and NodePackage work as on a loaded
+ * program. Returns the graph and its nodes by name.
+ * ============================================================================
+ */
+func Build(t testing.TB, specs ...string) (*cs_callgraph.Graph, map[string]*cs_callgraph.Node) {
+    t.Helper()
+
+    type edgeSpec struct {
+        from, to string
+        kind     cs_callgraph.EdgeKind
+    }
+    var names []string
+    var edges []edgeSpec
+    for _, spec := range specs {
+        fields := strings.Fields(spec)
+        switch len(fields) {
+        case 1:
+            names = append(names, fields[0])
+        case 3:
+            kind, ok := parseKind(fields[1])
+            if !ok {
+                t.Fatalf("bad edge %q", spec)
+            }
+            names = append(names, fields[0], fields[2])
+            edges = append(edges, edgeSpec{fields[0], fields[2], kind})
+        default:
+            t.Fatalf("bad spec %q", spec)
+        }
+    }
+
+    // One types.Package per path, populated before SSA creation
+    pkgs  := map[string]*types.Package{}
+    funcs := map[string]*types.Func{}
+    sig   := types.NewSignatureType(nil, nil, nil, nil, nil, false)
+    for _, name := range names {
+        if _, ok := funcs[name]; ok {
+            continue
+        }
+        dot := strings.LastIndex(name, ".")
+        if dot < 0 {
+            t.Fatalf("function %q has no package", name)
+        }
+        path := name[:dot]
+        pkg, ok := pkgs[path]
+        if !ok {
+            pkg = types.NewPackage(path, path[strings.LastIndex(path, "/")+1:])
+            pkgs[path] = pkg
+        }
+        fn := types.NewFunc(token.NoPos, pkg, name[dot+1:], sig)
+        pkg.Scope().Insert(fn)
+        funcs[name] = fn
+    }
+
+    prog := ssa.NewProgram(token.NewFileSet(), 0)
+    for _, pkg := range pkgs {
+        pkg.MarkComplete()
+        prog.CreatePackage(pkg, nil, nil, true)
+    }
+
+    g     := cs_callgraph.InitGraph(nil)
+    nodes := map[string]*cs_callgraph.Node{}
+    for _, name := range names {
+        if _, ok := nodes[name]; !ok {
+            nodes[name] = g.GenNode(prog.FuncValue(funcs[name]))
+        }
+    }
+    for _, e := range edges {
+        cs_callgraph.GenEdge(nodes[e.from], nil, nodes[e.to], e.kind)
+    }
+    return g, nodes
+}
+
+func parseKind(arrow string) (cs_callgraph.EdgeKind, bool) {
+    if arrow == "->" {
+        return cs_callgraph.CallEdge, true
+    }
+    name := strings.TrimSuffix(strings.TrimPrefix(arrow, "-"), "->")
+    for k := cs_callgraph.CallEdge; k <= cs_callgraph.InstanceEdge; k++ {
+        if k.String() == name {
+            return k, true
+        }
+    }
+    return 0, false
+}
+
+// Names returns the QualifiedName of each node
+func Names(nodes []*cs_callgraph.Node) []string {
+    out := make([]string, len(nodes))
+    for i, n := range nodes {
+        out[i] = n.QualifiedName()
+    }
+    return out
+}
@@ -0,0 +1,286 @@
+package cs_callgraph
+
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+/* ============================================================================
+ * Channel flow
+ * ----------------------------------------------------------------------------
+ * A function value sent into a channel already gets a SendEdge from the
+ * sender. chanFlow pairs those sends with the receives that call the value,
+ * so the receiving function gets a ReceiveEdge to every function that may
+ * arrive through the channel.
+ *
+ *   sends     func values sent on a channel, with the channel operand
+ *   receives  <-ch whose result is (eventually) called, with the calls
+ *
+ * Both directions are also read from the states of a select statement.
+ *
+ * Channels are identified by their MakeChan allocation site where it can be
+ * found (see chanAllocs); otherwise by element type.
+ * ============================================================================
+ */
+type chanFlow struct {
+    genMode  GenericsMode
+    sends    []chanSend
+    receives []chanRecv
+}
+
+type chanSend struct {
+    ch ssa.Value
+    fn *ssa.Function
+}
+
+type chanRecv struct {
+    receiver *Node
+    ch       ssa.Value
+    calls    []ssa.CallInstruction
+}
+
+// Levels of parameter passing followed back to find a channel's MakeChan
+const chanChaseDepth = 3
+
+/* ============================================================================
+ * observe
+ * ----------------------------------------------------------------------------
+ * Records instr if it sends a function value or receives a value that is
+ * then called, including the send and receive states of a select. Called by
+ * visit for every instruction of a scanned function.
+ * ============================================================================
+ */
+func (cf *chanFlow) observe(caller *Node, instr ssa.Instruction) {
+    switch i := instr.(type) {
+    case *ssa.Send:
+        if fn, ok := isFuncValue(i.X, cf.genMode); ok {
+            cf.sends = append(cf.sends, chanSend{ch: i.Chan, fn: fn})
+        }
+
+    case *ssa.UnOp:
+        if i.Op != token.ARROW {
+            return
+        }
+        if calls := callsOfValue(i, 0); len(calls) > 0 {
+            cf.receives = append(cf.receives, chanRecv{receiver: caller, ch: i.X, calls: calls})
+        }
+
+    case *ssa.Select:
+        recvIdx := 0
+        for _, st := range i.States {
+            switch st.Dir {
+            case types.SendOnly:
+                if fn, ok := isFuncValue(st.Send, cf.genMode); ok {
+                    cf.sends = append(cf.sends, chanSend{ch: st.Chan, fn: fn})
+                }
+            case types.RecvOnly:
+                if calls := selectRecvCalls(i, recvIdx); len(calls) > 0 {
+                    cf.receives = append(cf.receives, chanRecv{receiver: caller, ch: st.Chan, calls: calls})
+                }
+                recvIdx++
+            }
+        }
+    }
+}
+
+/* -------------------------------------------------------
+ * selectRecvCalls
+ * Calls of the value received by the n-th receive state
+ * of sel. The select yields (index, recvOk, r0, r1, ...),
+ * so that value is Extract #2+n.
+ * ------------------------------------------------------- */
+func selectRecvCalls(sel *ssa.Select, n int) []ssa.CallInstruction {
+    refs := sel.Referrers()
+    if refs == nil {
+        return nil
+    }
+    var calls []ssa.CallInstruction
+    for _, ref := range *refs {
+        if ex, ok := ref.(*ssa.Extract); ok && ex.Index == 2+n {
+            calls = append(calls, callsOfValue(ex, 1)...)
+        }
+    }
+    return calls
+}
+
+/* ============================================================================
+ * callsOfValue
+ * ----------------------------------------------------------------------------
+ * Returns the call instructions whose callee is v, looking through the
+ * comma-ok Extract, struct field access, dereference and type assertion.
+ * Mirrors leadsToCall in the statistics package.
+ * ============================================================================
+ */
+func callsOfValue(v ssa.Value, depth int) []ssa.CallInstruction {
+    if depth > 8 {
+        return nil
+    }
+    refs := v.Referrers()
+    if refs == nil {
+        return nil
+    }
+
+    var calls []ssa.CallInstruction
+    for _, ref := range *refs {
+        switch r := ref.(type) {
+        case ssa.CallInstruction:
+            if r.Common().Value == v {
+                calls = append(calls, r)
+            }
+        case *ssa.Extract:
+            if r.Index == 0 {
+                calls = append(calls, callsOfValue(r, depth+1)...)
+            }
+        case *ssa.Field:
+            calls = append(calls, callsOfValue(r, depth+1)...)
+        case *ssa.FieldAddr:
+            calls = append(calls, callsOfValue(r, depth+1)...)
+        case *ssa.TypeAssert:
+            calls = append(calls, callsOfValue(r, depth+1)...)
+        case *ssa.UnOp:
+            if r.Op == token.MUL {
+                calls = append(calls, callsOfValue(r, depth+1)...)
+            }
+        }
+    }
+    return calls
+}
+
+/* ============================================================================
+ * resolveChannelFlow
+ * ----------------------------------------------------------------------------
+ * Adds a ReceiveEdge from every receiving function to each function sent on
+ * a matching channel, one site per call of the received value. Two channels
+ * match when their allocation sites are both known and overlap, or - if
+ * either side is unknown - when their element types are identical.
+ *
+ * Returns the targets that received a new edge so the caller can visit them.
+ * ============================================================================
+ */
+func resolveChannelFlow(
+    cg            *Graph,
+    cf            *chanFlow,
+    existingEdges map[edgeKey]*Edge,
+) []*ssa.Function {
+    if len(cf.sends) == 0 || len(cf.receives) == 0 {
+        return nil
+    }
+
+    type endpoint struct {
+        allocs map[*ssa.MakeChan]bool // nil = unknown
+        elem   types.Type
+    }
+    resolve := func(ch ssa.Value) endpoint {
+        ep := endpoint{elem: chanElem(ch.Type())}
+        if allocs, ok := chanAllocs(cg, ch, chanChaseDepth); ok {
+            ep.allocs = allocs
+        }
+        return ep
+    }
+    matches := func(a, b endpoint) bool {
+        if a.allocs != nil && b.allocs != nil {
+            for mc := range a.allocs {
+                if b.allocs[mc] {
+                    return true
+                }
+            }
+            return false
+        }
+        return a.elem != nil && b.elem != nil && types.Identical(a.elem, b.elem)
+    }
+
+    sendEnds := make([]endpoint, len(cf.sends))
+    for i, s := range cf.sends {
+        sendEnds[i] = resolve(s.ch)
+    }
+
+    var targets []*ssa.Function
+    for _, recv := range cf.receives {
+        recvEnd := resolve(recv.ch)
+        for i, s := range cf.sends {
+            if !matches(sendEnds[i], recvEnd) {
+                continue
+            }
+            callee := cg.GenNode(s.fn)
+            key    := edgeKey{from: recv.receiver, to: callee, kind: ReceiveEdge, prov: ProvSyntactic}
+            edge, exists := existingEdges[key]
+            if !exists {
+                edge = GenEdge(recv.receiver, nil, callee, ReceiveEdge)
+                existingEdges[key] = edge
+                targets = append(targets, s.fn)
+            }
+            for _, call := range recv.calls {
+                if !containsSite(edge.Sites, call) {
+                    edge.Sites = append(edge.Sites, call)
+                }
+            }
+        }
+    }
+    return targets
+}
+
+func chanElem(t types.Type) types.Type {
+    if ch, ok := t.Underlying().(*types.Chan); ok {
+        return ch.Elem()
+    }
+    return nil
+}
+
+/* ============================================================================
+ * chanAllocs
+ * ----------------------------------------------------------------------------
+ * Returns the MakeChan instructions a channel value may come from. A
+ * parameter is replaced by the matching argument at each call site of its
+ * function in cg (go statements included), up to depth levels. Returns
+ * false when any path leads somewhere else (globals, struct fields, free
+ * variables, ...), in which case the caller falls back to element types.
+ * ============================================================================
+ */
+func chanAllocs(cg *Graph, v ssa.Value, depth int) (map[*ssa.MakeChan]bool, bool) {
+    switch v := v.(type) {
+    case *ssa.MakeChan:
+        return map[*ssa.MakeChan]bool{v: true}, true
+
+    case *ssa.ChangeType:
+        return chanAllocs(cg, v.X, depth)
+
+    case *ssa.Parameter:
+        if depth <= 0 {
+            return nil, false
+        }
+        fnNode := cg.Nodes[v.Parent()]
+        if fnNode == nil {
+            return nil, false
+        }
+        idx    := paramIndex(v)
+        result := map[*ssa.MakeChan]bool{}
+        found  := false
+        for _, e := range fnNode.In {
+            if e.Kind != CallEdge && e.Kind != GoEdge && e.Kind != DeferEdge {
+                continue
+            }
+            for _, site := range e.Sites {
+                call, ok := site.(ssa.CallInstruction)
+                if !ok {
+                    continue
+                }
+                args := call.Common().Args
+                if idx < 0 || idx >= len(args) {
+                    return nil, false
+                }
+                allocs, ok := chanAllocs(cg, args[idx], depth-1)
+                if !ok {
+                    return nil, false
+                }
+                for mc := range allocs {
+                    result[mc] = true
+                }
+                found = true
+            }
+        }
+        return result, found
+    }
+    return nil, false
+}
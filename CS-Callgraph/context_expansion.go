@@ -0,0 +1,391 @@
+package cs_callgraph
+
+import (
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+/* ============================================================================
+ * ExpandContexts
+ * ----------------------------------------------------------------------------
+ * Derives a k-CFA view of a collapsed call graph: every node is identified
+ * by (function, last k call sites), so a higher-order helper such as
+ * apply(f) gets a separate node - and a separate callee set - for each
+ * calling context.
+ *
+ * Expansion walks the collapsed graph from the Root's entry points (which
+ * are mirrored onto the expanded Root), then from every function without
+ * callers, then from anything still unexpanded; each of these starts with
+ * an empty context. For a node (fn, ctx):
+ *
+ *   call-like edges     callee context = ctx + site, truncated to k
+ *   dispatch edges      callee context = ctx (no new site), as for any
+ *                       other synthetic edge
+ *   reference edges     (assign / send) point at the callee's
+ *                       empty-context node - nothing is called here
+ *   panic edges         go to the shared PanicNode
+ *   instance edges      point at the origin's empty-context node
+ *
+ * A call through a parameter only keeps the targets that the calling
+ * context actually passed in: the argument is chased back through the
+ * context's call sites (see originInContext). The same is done for the
+ * receiver of an interface dispatch. When the context runs out before the
+ * value is pinned down, every collapsed target is kept. Calls through a
+ * parameter get their collapsed targets from FuncValVTA; a base built with
+ * FuncValSyntactic has no edge there, so its callbacks are not split.
+ *
+ * mode must be the GenericsMode base was built with, so that function values
+ * resolve to the same nodes.
+ *
+ * Returns base unchanged if k <= 0.
+ * ============================================================================
+ */
+func ExpandContexts(base *Graph, k int, mode GenericsMode) *Graph {
+    if k <= 0 {
+        return base
+    }
+
+    x := &ctxExpander{
+        base:    base,
+        k:       k,
+        mode:    mode,
+        out:     InitGraph(nil),
+        index:   map[ctxKey]*Node{},
+        covered: map[*Node]bool{},
+        edges:   map[edgeKey]*Edge{},
+        siteIDs: map[ssa.CallInstruction]int{},
+    }
+    x.out.K   = k
+    x.out.Std = base.Std
+    x.nextFn = len(x.out.Nodes) // IDs after the expanded Root
+
+    /* -------------------------------------------------------
+     * Roots: entry functions, functions no edge points at, then
+     * any collapsed node not yet reached (e.g. isolated cycles).
+     * ------------------------------------------------------- */
+    for _, e := range base.Root.Out {
+        x.link(x.out.Root, x.node(e.Callee, nil), e, nil)
+    }
+    x.drain()
+
+    var roots []*Node
+    for _, n := range base.FunctionNodes() {
+        if n.Func != nil && len(n.In) == 0 {
+            roots = append(roots, n)
+        }
+    }
+    for _, n := range roots {
+        x.node(n, nil)
+        x.drain()
+    }
+    for _, n := range append(base.FunctionNodes(), base.InterfaceNodes()...) {
+        if n.Func == nil && n.IfaceMethod == nil {
+            continue
+        }
+        if !x.covered[n] {
+            x.node(n, nil)
+            x.drain()
+        }
+    }
+
+    sort.Slice(x.out.Contexts, func(i, j int) bool {
+        return x.out.Contexts[i].ID < x.out.Contexts[j].ID
+    })
+    return x.out
+}
+
+/* ============================================================================
+ * ctxExpander
+ * ----------------------------------------------------------------------------
+ * Working state for ExpandContexts.
+ *
+ *   mode     GenericsMode of base, for resolving function values
+ *   index    (collapsed node, context) → expanded node
+ *   covered  collapsed nodes with at least one expanded node
+ *   edges    dedup for expanded edges, as in BuildExtendedCallGraph2
+ *   siteIDs  stable small integers for call sites, used to key contexts
+ *   queue    expanded nodes whose out-edges have not been built yet
+ * ============================================================================
+ */
+type ctxExpander struct {
+    base    *Graph
+    k       int
+    mode    GenericsMode
+    out     *Graph
+    index   map[ctxKey]*Node
+    covered map[*Node]bool
+    edges   map[edgeKey]*Edge
+    siteIDs map[ssa.CallInstruction]int
+    queue   []*Node
+    nextFn  int
+    nextIfc int
+}
+
+type ctxKey struct {
+    base *Node
+    ctx  string
+}
+
+func (x *ctxExpander) key(base *Node, ctx []ssa.CallInstruction) ctxKey {
+    ids := make([]string, len(ctx))
+    for i, site := range ctx {
+        id, ok := x.siteIDs[site]
+        if !ok {
+            id = len(x.siteIDs)
+            x.siteIDs[site] = id
+        }
+        ids[i] = strconv.Itoa(id)
+    }
+    return ctxKey{base: base, ctx: strings.Join(ids, ",")}
+}
+
+/* ============================================================================
+ * node
+ * ----------------------------------------------------------------------------
+ * Returns the expanded node for (base, ctx), creating and queueing it if new.
+ * Empty-context nodes are also registered in Nodes / IfaceNodes so lookups
+ * by function (e.g. the main entry) work on the expanded graph.
+ * ============================================================================
+ */
+func (x *ctxExpander) node(base *Node, ctx []ssa.CallInstruction) *Node {
+    key := x.key(base, ctx)
+    if n, ok := x.index[key]; ok {
+        return n
+    }
+
+    n := &Node{
+        Func:        base.Func,
+        IfaceMethod: base.IfaceMethod,
+        Body:        base.Body,
+        Sink:        base.Sink,
+        Context:     ctx,
+        Base:        base,
+    }
+    if base.IfaceMethod != nil {
+        n.ID = -(x.nextIfc + 100)
+        x.nextIfc++
+        if len(ctx) == 0 {
+            x.out.IfaceNodes[base.IfaceMethod] = n
+        }
+    } else {
+        n.ID = x.nextFn
+        x.nextFn++
+        if len(ctx) == 0 {
+            x.out.Nodes[base.Func] = n
+        }
+    }
+
+    x.index[key] = n
+    x.covered[base] = true
+    x.out.Contexts = append(x.out.Contexts, n)
+    x.queue = append(x.queue, n)
+    return n
+}
+
+/* ============================================================================
+ * drain
+ * ----------------------------------------------------------------------------
+ * Builds out-edges for every queued node, queueing the callees it creates.
+ * ============================================================================
+ */
+func (x *ctxExpander) drain() {
+    for len(x.queue) > 0 {
+        cur := x.queue[0]
+        x.queue = x.queue[1:]
+        x.expandNode(cur)
+    }
+}
+
+func (x *ctxExpander) expandNode(n *Node) {
+    for _, e := range n.Base.Out {
+        if e.Callee == nil {
+            continue
+        }
+
+        if e.Kind == PanicEdge {
+            for _, site := range e.Sites {
+                x.link(n, x.out.PanicNode, e, site)
+            }
+            continue
+        }
+
+        if e.Kind == InstanceEdge {
+            x.link(n, x.node(e.Callee, nil), e, nil)
+            continue
+        }
+        if e.Kind == DispatchEdge || len(e.Sites) == 0 {
+            if x.dispatchAllowed(n, e.Callee) {
+                x.link(n, x.node(e.Callee, n.Context), e, nil)
+            }
+            continue
+        }
+
+        for _, site := range e.Sites {
+            call, isCall := site.(ssa.CallInstruction)
+            if !isCall || !isCallLike(e.Kind) {
+                x.link(n, x.node(e.Callee, nil), e, site)
+                continue
+            }
+            if !x.callAllowed(n, call, e.Callee) {
+                continue
+            }
+            x.link(n, x.node(e.Callee, pushContext(n.Context, call, x.k)), e, site)
+        }
+    }
+}
+
+/* ============================================================================
+ * link
+ * ----------------------------------------------------------------------------
+ * Adds (or extends) an expanded edge mirroring collapsed edge e.
+ * ============================================================================
+ */
+func (x *ctxExpander) link(from, to *Node, e *Edge, site ssa.Instruction) {
+    key := edgeKey{from: from, to: to, kind: e.Kind, prov: e.Prov}
+    if edge, ok := x.edges[key]; ok {
+        if site != nil && !containsSite(edge.Sites, site) {
+            edge.Sites = append(edge.Sites, site)
+        }
+        return
+    }
+    edge := GenEdge(from, site, to, e.Kind)
+    edge.Prov = e.Prov
+    x.edges[key] = edge
+}
+
+/* ============================================================================
+ * callAllowed
+ * ----------------------------------------------------------------------------
+ * Reports whether the collapsed target of a dynamic call is consistent with
+ * n's context. Static calls and interface invokes are always allowed.
+ * ============================================================================
+ */
+func (x *ctxExpander) callAllowed(n *Node, call ssa.CallInstruction, target *Node) bool {
+    common := call.Common()
+    if common.IsInvoke() || common.StaticCallee() != nil || target.Func == nil {
+        return true
+    }
+    origin, ok := originInContext(common.Value, n.Context)
+    if !ok {
+        return true
+    }
+    fn, ok := isFuncValue(origin, x.mode)
+    if !ok {
+        return true
+    }
+    return fn == target.Func
+}
+
+/* ============================================================================
+ * dispatchAllowed
+ * ----------------------------------------------------------------------------
+ * Reports whether an interface node in context n.Context may dispatch to
+ * impl. The receiver of the invoke at the innermost context site is chased
+ * back to a MakeInterface; if its concrete type is known, only the method
+ * that type provides is allowed.
+ * ============================================================================
+ */
+func (x *ctxExpander) dispatchAllowed(n *Node, impl *Node) bool {
+    if len(n.Context) == 0 || impl.Func == nil || n.IfaceMethod == nil {
+        return true
+    }
+    site := n.Context[len(n.Context)-1]
+    if !site.Common().IsInvoke() {
+        return true
+    }
+    origin, ok := originInContext(site.Common().Value, n.Context[:len(n.Context)-1])
+    if !ok {
+        return true
+    }
+    mi, ok := origin.(*ssa.MakeInterface)
+    if !ok {
+        return true
+    }
+
+    prog := impl.Func.Prog
+    sel  := prog.MethodSets.MethodSet(mi.X.Type()).Lookup(
+        n.IfaceMethod.Pkg(), n.IfaceMethod.Name(),
+    )
+    if sel == nil {
+        return true
+    }
+    obj, ok := sel.Obj().(*types.Func)
+    if !ok {
+        return true
+    }
+    return resolveServiceableFunc(prog.FuncValue(obj), x.mode) == impl.Func
+}
+
+/* ============================================================================
+ * originInContext
+ * ----------------------------------------------------------------------------
+ * Follows a value back through parameters: a parameter of the current
+ * function is replaced by the matching argument at the innermost context
+ * site, in the caller's (shorter) context, until the value is no longer a
+ * parameter. Returns false when the context is exhausted first.
+ * ============================================================================
+ */
+func originInContext(v ssa.Value, ctx []ssa.CallInstruction) (ssa.Value, bool) {
+    for {
+        if ct, ok := v.(*ssa.ChangeType); ok {
+            v = ct.X
+            continue
+        }
+        param, ok := v.(*ssa.Parameter)
+        if !ok {
+            return v, true
+        }
+        if len(ctx) == 0 {
+            return nil, false
+        }
+
+        site   := ctx[len(ctx)-1]
+        common := site.Common()
+        idx    := paramIndex(param)
+        if common.IsInvoke() {
+            idx-- // Args exclude the receiver, Params of the method do not
+        }
+        if idx < 0 || idx >= len(common.Args) {
+            return nil, false
+        }
+        v   = common.Args[idx]
+        ctx = ctx[:len(ctx)-1]
+    }
+}
+
+func paramIndex(p *ssa.Parameter) int {
+    for i, q := range p.Parent().Params {
+        if q == p {
+            return i
+        }
+    }
+    return -1
+}
+
+/* ============================================================================
+ * isCallLike / pushContext
+ * ----------------------------------------------------------------------------
+ * isCallLike reports whether an edge kind transfers control at its site
+ * (and therefore extends the call string). pushContext appends a site to a
+ * context, keeping only the innermost k entries.
+ * ============================================================================
+ */
+func isCallLike(kind EdgeKind) bool {
+    switch kind {
+    case CallEdge, GoEdge, DeferEdge, InterfaceEdge, ReflectEdge, ReceiveEdge:
+        return true
+    }
+    return false
+}
+
+func pushContext(ctx []ssa.CallInstruction, site ssa.CallInstruction, k int) []ssa.CallInstruction {
+    next := append(append([]ssa.CallInstruction{}, ctx...), site)
+    if len(next) > k {
+        next = next[len(next)-k:]
+    }
+    return next
+}
@@ -2,7 +2,7 @@ package cs_callgraph
 
 import (
 	"fmt"
-	"os"
+	"io"
 	"strings"
 
 	"golang.org/x/tools/go/ssa"
@@ -16,28 +16,68 @@ import (
  *
  * Any package not reachable from this package's import chain is excluded.
  * Internal project packages are clamped to depth 0, while external or standard
- * library dependencies scale outward (+1 depth per hop).
+ * library dependencies scale outward (+1 depth per hop). Progress is written
+ * to log.
  * ============================================================================
  */
 func BuildPackageDepthMapFromMain(
     prog        *ssa.Program,
     projectRoot string,
     mainPkg     *ssa.Package,
-) map[string]int {
+    log         io.Writer,
+) (map[string]int, error) {
     // Safety check to ensure the passed package structure is initialized
     if mainPkg == nil || mainPkg.Pkg == nil {
-        fmt.Printf("[DepthMap] error: provided main package is nil or uninitialized\n")
-        os.Exit(-1)
+        return nil, fmt.Errorf("provided main package is nil or uninitialized")
+    }
+
+    fmt.Fprintf(log, "[DepthMap] Building depth map rooted at entry package: %s\n", mainPkg.Pkg.Path())
+    return buildDepthMap(prog, projectRoot, []*ssa.Package{mainPkg}), nil
+}
+
+/* ============================================================================
+ * BuildPackageDepthMapFromRoots
+ * ----------------------------------------------------------------------------
+ * Same traversal as BuildPackageDepthMapFromMain, seeded with several root
+ * packages at depth 0 (e.g. the packages of a library in library mode).
+ * ============================================================================
+ */
+func BuildPackageDepthMapFromRoots(
+    prog        *ssa.Program,
+    projectRoot string,
+    roots       []*ssa.Package,
+    log         io.Writer,
+) (map[string]int, error) {
+    if len(roots) == 0 {
+        return nil, fmt.Errorf("no root packages provided")
     }
 
-    fmt.Printf("[DepthMap] Building depth map rooted at entry package: %s\n", mainPkg.Pkg.Path())
+    fmt.Fprintf(log, "[DepthMap] Building depth map rooted at %d package(s)\n", len(roots))
+    return buildDepthMap(prog, projectRoot, roots), nil
+}
 
+/* ============================================================================
+ * buildDepthMap
+ * ----------------------------------------------------------------------------
+ * BFS over the import graph from the given roots (all at depth 0).
+ * ============================================================================
+ */
+func buildDepthMap(
+    prog        *ssa.Program,
+    projectRoot string,
+    roots       []*ssa.Package,
+) map[string]int {
     depthMap := map[string]int{}
     queue    := []*ssa.Package{}
 
-    // Seed the traversal exclusively with our main package at depth 0
-    depthMap[mainPkg.Pkg.Path()] = 0
-    queue = append(queue, mainPkg)
+    // Seed the traversal exclusively with the root packages at depth 0
+    for _, root := range roots {
+        if root == nil || root.Pkg == nil {
+            continue
+        }
+        depthMap[root.Pkg.Path()] = 0
+        queue = append(queue, root)
+    }
 
     for len(queue) > 0 {
         current := queue[0]
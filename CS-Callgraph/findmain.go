@@ -2,7 +2,7 @@ package cs_callgraph
 
 import (
 	"fmt"
-	"os"
+	"io"
 	"sort"
 	"strings"
 
@@ -14,15 +14,23 @@ type FoundMain struct {
     Funct *ssa.Function
 }
 
+/* ============================================================================
+ * ResolveMain
+ * ----------------------------------------------------------------------------
+ * Returns the main named by mainFlag, or the best candidate under
+ * projectRoot if it is empty. Progress is written to log.
+ * ============================================================================
+ */
 func ResolveMain(
 	prog        *ssa.Program,
 	projectRoot string,
 	mainFlag    string,
-) *FoundMain {
+	log         io.Writer,
+) (*FoundMain, error) {
 	if mainFlag != "" {
-		return resolveExplicitMain(prog, mainFlag)
+		return resolveExplicitMain(prog, mainFlag, log)
 	}
-	return findPossibleMain(prog, projectRoot)
+	return findPossibleMain(prog, projectRoot, log)
 }
 
 /* ============================================================================
@@ -32,33 +40,29 @@ func ResolveMain(
  * (e.g. "github.com/restic/restic/cmd/restic.main") and checks if it exists.
  * ============================================================================
  */
-func resolveExplicitMain(prog *ssa.Program, mainFlag string) *FoundMain {
+func resolveExplicitMain(prog *ssa.Program, mainFlag string, log io.Writer) (*FoundMain, error) {
     lastDot := strings.LastIndex(mainFlag, ".")
     if lastDot == -1 {
-        fmt.Printf("[ResolveMain] invalid explicit main flag format: %s\n", mainFlag)
-        os.Exit(-1)
+        return nil, fmt.Errorf("invalid entry point format %q (want pkg/path.Func)", mainFlag)
     }
     targetPkgPath := mainFlag[:lastDot]
     impPkg := prog.ImportedPackage(targetPkgPath)
     if impPkg == nil {
-        fmt.Printf("[ResolveMain] error: package %q not found\n", targetPkgPath)
-        os.Exit(-1)
+        return nil, fmt.Errorf("package %q not found", targetPkgPath)
     }
     mainPkg := prog.Package(impPkg.Pkg)
     if mainPkg == nil || mainPkg.Pkg == nil {
-        fmt.Printf("[ResolveMain] error: structural package missing for %q\n", targetPkgPath)
-        os.Exit(-1)
+        return nil, fmt.Errorf("structural package missing for %q", targetPkgPath)
     }
 
     funcName := mainFlag[lastDot+1:]
     mainFunc := mainPkg.Func(funcName)
     if mainFunc == nil {
-        fmt.Printf("[ResolveMain] error: function %q not found in package %q\n", funcName, targetPkgPath)
-        os.Exit(-1)
+        return nil, fmt.Errorf("function %q not found in package %q", funcName, targetPkgPath)
     }
 
-    fmt.Printf("[ResolveMain] Exact match: %s\n", mainFlag)
-    return &FoundMain{Packg: mainPkg, Funct: mainFunc}
+    fmt.Fprintf(log, "[ResolveMain] Exact match: %s\n", mainFlag)
+    return &FoundMain{Packg: mainPkg, Funct: mainFunc}, nil
 }
 
 
@@ -74,13 +78,100 @@ func resolveExplicitMain(prog *ssa.Program, mainFlag string) *FoundMain {
  * ============================================================================
  */
 
-func findPossibleMain(prog *ssa.Program, projectRoot string) *FoundMain {
-    type candidate struct {
-        pkg  *ssa.Package
-        fn   *ssa.Function
+func findPossibleMain(prog *ssa.Program, projectRoot string, log io.Writer) (*FoundMain, error) {
+    priority1, priority2 := mainCandidates(prog, projectRoot)
+
+    total := len(priority1) + len(priority2)
+    if total > 0 {
+        fmt.Fprintf(log, "[ResolveMain] Found %d potential main(s):\n", total)
+        for _, c := range priority1 {
+            fmt.Fprintf(log, "  -> [Priority 1]: %s.main\n", c.Packg.Pkg.Path())
+        }
+        for _, c := range priority2 {
+            fmt.Fprintf(log, "  -> [Priority 2]: %s.main\n", c.Packg.Pkg.Path())
+        }
+    }
+    if len(priority1) > 1 || (len(priority1) == 0 && len(priority2) > 1) {
+        fmt.Fprintln(log, "[WARNING]: Multiple possible main packages found.")
+    }
+
+    if len(priority1) > 0 {
+        fmt.Fprintf(log, "[ResolveMain] selected: %s\n", priority1[0].Packg.Pkg.Path())
+        return priority1[0], nil
+    }
+    if len(priority2) > 0 {
+        fmt.Fprintf(log, "[ResolveMain] selected: %s\n", priority2[0].Packg.Pkg.Path())
+        return priority2[0], nil
+    }
+    return nil, fmt.Errorf("no main function found under %q", projectRoot)
+}
+
+/* ============================================================================
+ * ResolveAllMains
+ * ----------------------------------------------------------------------------
+ * Returns every Priority 1 main followed by every Priority 2 main, each group
+ * sorted by package path. Fails if the project has no main at all. Progress
+ * is written to log.
+ * ============================================================================
+ */
+func ResolveAllMains(prog *ssa.Program, projectRoot string, log io.Writer) ([]*FoundMain, error) {
+    priority1, priority2 := mainCandidates(prog, projectRoot)
+    all := append(priority1, priority2...)
+    if len(all) == 0 {
+        return nil, fmt.Errorf("no main function found under %q", projectRoot)
+    }
+
+    fmt.Fprintf(log, "[ResolveMain] Using all %d main(s):\n", len(all))
+    for _, c := range all {
+        fmt.Fprintf(log, "  -> %s.main\n", c.Packg.Pkg.Path())
     }
-    var priority1, priority2 []candidate
+    return all, nil
+}
+
+/* ============================================================================
+ * ResolveEntry
+ * ----------------------------------------------------------------------------
+ * Resolves an additional entry point given in the same fully qualified form
+ * as the -main flag (e.g. "github.com/you/repo/worker.Run"). Progress is
+ * written to log.
+ * ============================================================================
+ */
+func ResolveEntry(prog *ssa.Program, name string, log io.Writer) (*FoundMain, error) {
+    return resolveExplicitMain(prog, name, log)
+}
 
+/* ============================================================================
+ * PackageInits
+ * ----------------------------------------------------------------------------
+ * Returns the synthetic init function of every package whose path passes
+ * inScope, sorted by package path. These run before any main and are
+ * entry points in their own right.
+ * ============================================================================
+ */
+func PackageInits(prog *ssa.Program, inScope func(string) bool) []*ssa.Function {
+    var inits []*ssa.Function
+    for _, pkg := range prog.AllPackages() {
+        if pkg.Pkg == nil || !inScope(pkg.Pkg.Path()) {
+            continue
+        }
+        if fn := pkg.Func("init"); fn != nil {
+            inits = append(inits, fn)
+        }
+    }
+    sort.Slice(inits, func(i, j int) bool {
+        return inits[i].Pkg.Pkg.Path() < inits[j].Pkg.Pkg.Path()
+    })
+    return inits
+}
+
+/* ============================================================================
+ * mainCandidates
+ * ----------------------------------------------------------------------------
+ * Collects every main() within projectRoot, split into Priority 1 (package
+ * named "main") and Priority 2 (any other package), each sorted by path.
+ * ============================================================================
+ */
+func mainCandidates(prog *ssa.Program, projectRoot string) (priority1, priority2 []*FoundMain) {
     for _, pkg := range prog.AllPackages() {
         if pkg.Pkg == nil {
             continue
@@ -95,7 +186,7 @@ func findPossibleMain(prog *ssa.Program, projectRoot string) *FoundMain {
         if mainFunc == nil {
             continue
         }
-        c := candidate{pkg: pkg, fn: mainFunc}
+        c := &FoundMain{Packg: pkg, Funct: mainFunc}
         if pkg.Pkg.Name() == "main" {
             priority1 = append(priority1, c)
         } else {
@@ -104,36 +195,10 @@ func findPossibleMain(prog *ssa.Program, projectRoot string) *FoundMain {
     }
 
     sort.Slice(priority1, func(i, j int) bool {
-        return priority1[i].pkg.Pkg.Path() < priority1[j].pkg.Pkg.Path()
+        return priority1[i].Packg.Pkg.Path() < priority1[j].Packg.Pkg.Path()
     })
     sort.Slice(priority2, func(i, j int) bool {
-        return priority2[i].pkg.Pkg.Path() < priority2[j].pkg.Pkg.Path()
+        return priority2[i].Packg.Pkg.Path() < priority2[j].Packg.Pkg.Path()
     })
-
-    total := len(priority1) + len(priority2)
-    if total > 0 {
-        fmt.Printf("[ResolveMain] Found %d potential main(s):\n", total)
-        for _, c := range priority1 {
-            fmt.Printf("  -> [Priority 1]: %s.main\n", c.pkg.Pkg.Path())
-        }
-        for _, c := range priority2 {
-            fmt.Printf("  -> [Priority 2]: %s.main\n", c.pkg.Pkg.Path())
-        }
-    }
-    if len(priority1) > 1 || (len(priority1) == 0 && len(priority2) > 1) {
-        fmt.Println("[WARNING]: Multiple possible main packages found.")
-    }
-
-    if len(priority1) > 0 {
-        fmt.Printf("[ResolveMain] selected: %s\n", priority1[0].pkg.Pkg.Path())
-        return &FoundMain{Packg: priority1[0].pkg, Funct: priority1[0].fn}
-    }
-    if len(priority2) > 0 {
-        fmt.Printf("[ResolveMain] selected: %s\n", priority2[0].pkg.Pkg.Path())
-        return &FoundMain{Packg: priority2[0].pkg, Funct: priority2[0].fn}
-    }
-
-    fmt.Printf("[ResolveMain] error: no main found\n")
-    os.Exit(-1)
-    return nil
-}
\ No newline at end of file
+    return priority1, priority2
+}
@@ -0,0 +1,159 @@
+package cs_callgraph
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/vta"
+	"golang.org/x/tools/go/ssa"
+)
+
+/* ============================================================================
+ * FuncValueMode
+ * ----------------------------------------------------------------------------
+ * Selects how calls through function-valued variables are resolved.
+ *
+ *   FuncValSyntactic  only calls where isFuncValue can see a literal
+ *                     *ssa.Function / MakeClosure at the call site
+ *   FuncValVTA        additionally run variable-type analysis over every
+ *                     scanned function and add its targets for the
+ *                     remaining dynamic call sites
+ * ============================================================================
+ */
+type FuncValueMode int
+
+const (
+    FuncValSyntactic FuncValueMode = iota
+    FuncValVTA
+)
+
+func (m FuncValueMode) String() string {
+    switch m {
+    case FuncValSyntactic: return "syntactic"
+    case FuncValVTA:       return "vta"
+    default:               return "unknown"
+    }
+}
+
+/* ============================================================================
+ * ParseFuncValueMode
+ * ----------------------------------------------------------------------------
+ * Converts a flag value ("syntactic", "vta") into a FuncValueMode.
+ * ============================================================================
+ */
+func ParseFuncValueMode(s string) (FuncValueMode, error) {
+    switch s {
+    case "syntactic": return FuncValSyntactic, nil
+    case "vta":       return FuncValVTA, nil
+    }
+    return FuncValSyntactic, fmt.Errorf("unknown function value resolution mode %q (want syntactic or vta)", s)
+}
+
+/* ============================================================================
+ * isUnresolvedFuncCall
+ * ----------------------------------------------------------------------------
+ * Reports whether a call site goes through a function value that the
+ * syntactic pass in extractEdges could not pin to a single function.
+ * Interface invokes are excluded - those are handled by IfaceMode.
+ * ============================================================================
+ */
+func isUnresolvedFuncCall(call *ssa.CallCommon, mode GenericsMode) bool {
+    if call.IsInvoke() || call.StaticCallee() != nil {
+        return false
+    }
+    if _, isBuiltin := call.Value.(*ssa.Builtin); isBuiltin {
+        return false
+    }
+    _, resolved := isFuncValue(call.Value, mode)
+    return !resolved
+}
+
+/* ============================================================================
+ * siteEdgeKind
+ * ----------------------------------------------------------------------------
+ * Returns the edge kind a call instruction produces (go / defer / call).
+ * ============================================================================
+ */
+func siteEdgeKind(site ssa.CallInstruction) EdgeKind {
+    switch site.(type) {
+    case *ssa.Go:    return GoEdge
+    case *ssa.Defer: return DeferEdge
+    default:         return CallEdge
+    }
+}
+
+/* ============================================================================
+ * resolveFuncValuesVTA
+ * ----------------------------------------------------------------------------
+ * Runs VTA over every function whose body has been scanned and adds a
+ * ProvVTA edge for each target of a call site that the syntactic pass left
+ * unresolved. initial (the CHA call graph of the whole program, built once
+ * by the caller) seeds VTA's interprocedural flow.
+ *
+ * Targets in unknown (out of scope or skipped) packages are dropped, as in
+ * resolveIfaceDispatch. Returns the targets that received a new edge so the
+ * caller can visit them.
+ * ============================================================================
+ */
+func resolveFuncValuesVTA(
+    cg            *Graph,
+    scanned       map[*ssa.Function]bool,
+    initial       *callgraph.Graph,
+    existingEdges map[edgeKey]*Edge,
+    pkgStatus     func(string) (bool, bool),
+    genMode       GenericsMode,
+) []*ssa.Function {
+    result := vta.CallGraph(scanned, initial)
+
+    var targets []*ssa.Function
+    for fn := range scanned {
+        vtaNode := result.Nodes[fn]
+        if vtaNode == nil {
+            continue
+        }
+        callerNode := cg.GenNode(fn)
+
+        for _, out := range vtaNode.Out {
+            if out.Site == nil || out.Callee == nil || out.Callee.Func == nil {
+                continue
+            }
+            if !isUnresolvedFuncCall(out.Site.Common(), genMode) {
+                continue
+            }
+
+            target := resolveServiceableFunc(out.Callee.Func, genMode)
+            pkg    := EffectivePkg(target)
+            if pkg == nil || pkg.Pkg == nil {
+                continue
+            }
+            if known, _ := pkgStatus(pkg.Pkg.Path()); !known {
+                continue
+            }
+
+            callee := cg.GenNode(target)
+            kind   := siteEdgeKind(out.Site)
+            key    := edgeKey{from: callerNode, to: callee, kind: kind, prov: ProvVTA}
+
+            if edge, exists := existingEdges[key]; exists {
+                if !containsSite(edge.Sites, out.Site) {
+                    edge.Sites = append(edge.Sites, out.Site)
+                }
+                continue
+            }
+            edge := GenEdge(callerNode, out.Site, callee, kind)
+            edge.Prov = ProvVTA
+            existingEdges[key] = edge
+            targets = append(targets, target)
+        }
+    }
+    return targets
+}
+
+func containsSite(sites []ssa.Instruction, site ssa.Instruction) bool {
+    for _, s := range sites {
+        if s == site {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,114 @@
+package cs_callgraph
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+/* ============================================================================
+ * GenericsMode
+ * ----------------------------------------------------------------------------
+ * Selects how generic functions are represented.
+ *
+ *   GenericsOrigin     every instantiation is folded onto its generic origin
+ *                      (one node per generic function)
+ *   GenericsInstances  one node per instantiation, e.g. Map[int] and
+ *                      Map[string]; each links to its origin with an
+ *                      InstanceEdge. Requires the SSA program to be built
+ *                      with ssa.InstantiateGenerics, otherwise instances
+ *                      have no body and are folded as in GenericsOrigin.
+ * ============================================================================
+ */
+type GenericsMode int
+
+const (
+    GenericsOrigin GenericsMode = iota
+    GenericsInstances
+)
+
+func (m GenericsMode) String() string {
+    switch m {
+    case GenericsOrigin:    return "origin"
+    case GenericsInstances: return "instances"
+    default:                return "unknown"
+    }
+}
+
+/* ============================================================================
+ * ParseGenericsMode
+ * ----------------------------------------------------------------------------
+ * Converts a flag value ("origin", "instances") into a GenericsMode.
+ * ============================================================================
+ */
+func ParseGenericsMode(s string) (GenericsMode, error) {
+    switch s {
+    case "origin":    return GenericsOrigin, nil
+    case "instances": return GenericsInstances, nil
+    }
+    return GenericsOrigin, fmt.Errorf("unknown generics mode %q (want origin or instances)", s)
+}
+
+/* ============================================================================
+ * isInstance / isGenericBody
+ * ----------------------------------------------------------------------------
+ * isInstance reports whether fn is an instantiation with its own body (only
+ * built under ssa.InstantiateGenerics). isGenericBody reports whether fn's
+ * body is written in terms of type parameters - a generic function, or a
+ * closure inside one.
+ * ============================================================================
+ */
+func isInstance(fn *ssa.Function) bool {
+    origin := fn.Origin()
+    return origin != nil && origin != fn && len(fn.Blocks) > 0
+}
+
+func isGenericBody(fn *ssa.Function) bool {
+    for ; fn != nil; fn = fn.Parent() {
+        if len(fn.TypeArgs()) > 0 {
+            return false // instances share TypeParams with their origin
+        }
+        if fn.TypeParams().Len() > 0 {
+            return true
+        }
+    }
+    return false
+}
+
+/* ============================================================================
+ * linkInstance
+ * ----------------------------------------------------------------------------
+ * Adds the InstanceEdge from an instantiation node to its origin's node.
+ * The origin is only given a node, never visited: under GenericsInstances
+ * its body is represented by the instances.
+ * ============================================================================
+ */
+func linkInstance(cg *Graph, n *Node, existingEdges map[edgeKey]*Edge) {
+    if n.Func == nil || !isInstance(n.Func) {
+        return
+    }
+    origin := cg.GenNode(n.Func.Origin())
+    key    := edgeKey{from: n, to: origin, kind: InstanceEdge, prov: ProvSyntactic}
+    if _, exists := existingEdges[key]; exists {
+        return
+    }
+    existingEdges[key] = GenEdge(n, nil, origin, InstanceEdge)
+}
+
+/* ============================================================================
+ * Instances
+ * ----------------------------------------------------------------------------
+ * Returns the instantiation nodes linked to an origin node, sorted by ID.
+ * ============================================================================
+ */
+func (n *Node) Instances() []*Node {
+    var result []*Node
+    for _, e := range n.In {
+        if e.Kind == InstanceEdge {
+            result = append(result, e.Caller)
+        }
+    }
+    sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+    return result
+}
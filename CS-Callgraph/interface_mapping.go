@@ -1,9 +1,65 @@
 package cs_callgraph
 
 import (
+	"fmt"
 	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+/* ============================================================================
+ * IfaceMode
+ * ----------------------------------------------------------------------------
+ * Selects how interface method nodes are linked to the concrete methods that
+ * implement them.
+ *
+ *   IfaceNone  no dispatch edges - interface nodes are sinks
+ *   IfaceCHA   class-hierarchy analysis: every named type declared in an
+ *              in-scope package that implements the interface
+ *   IfaceRTA   rapid-type analysis: only types that are actually converted
+ *              to an interface somewhere in a scanned function body
+ * ============================================================================
+ */
+type IfaceMode int
+
+const (
+    IfaceNone IfaceMode = iota
+    IfaceCHA
+    IfaceRTA
 )
 
+func (m IfaceMode) String() string {
+    switch m {
+    case IfaceNone: return "none"
+    case IfaceCHA:  return "cha"
+    case IfaceRTA:  return "rta"
+    default:        return "unknown"
+    }
+}
+
+/* ============================================================================
+ * ParseIfaceMode
+ * ----------------------------------------------------------------------------
+ * Converts a flag value ("none", "cha", "rta") into an IfaceMode.
+ * ============================================================================
+ */
+func ParseIfaceMode(s string) (IfaceMode, error) {
+    switch s {
+    case "none": return IfaceNone, nil
+    case "cha":  return IfaceCHA, nil
+    case "rta":  return IfaceRTA, nil
+    }
+    return IfaceNone, fmt.Errorf("unknown interface resolution mode %q (want none, cha or rta)", s)
+}
+
+/* ============================================================================
+ * GenIfaceNode
+ * ----------------------------------------------------------------------------
+ * Returns the node for an interface method, creating it if it does not exist.
+ * Interface nodes use negative IDs so they never collide with function nodes.
+ * ============================================================================
+ */
 func (g *Graph) GenIfaceNode(m *types.Func) *Node {
     if m.Pkg() == nil {
         return nil
@@ -17,4 +73,134 @@ func (g *Graph) GenIfaceNode(m *types.Func) *Node {
     }
     g.IfaceNodes[m] = n
     return n
-}
\ No newline at end of file
+}
+
+/* ============================================================================
+ * methodIndex
+ * ----------------------------------------------------------------------------
+ * Candidate concrete types bucketed by the names of the methods in their
+ * method sets, so an interface method only has to be checked against types
+ * that could possibly provide it.
+ *
+ * Both T and *T are indexed for every named type added, since either may be
+ * the dynamic type stored in an interface.
+ * ============================================================================
+ */
+type methodIndex struct {
+    prog   *ssa.Program
+    mode   GenericsMode
+    seen   typeutil.Map
+    byName map[string][]types.Type
+}
+
+func newMethodIndex(prog *ssa.Program, mode GenericsMode) *methodIndex {
+    return &methodIndex{
+        prog:   prog,
+        mode:   mode,
+        byName: make(map[string][]types.Type),
+    }
+}
+
+/* ============================================================================
+ * add
+ * ----------------------------------------------------------------------------
+ * Registers a concrete type as a dispatch candidate. Interfaces, type
+ * parameters and uninstantiated generic types are ignored. Returns true if
+ * the type was not already indexed.
+ * ============================================================================
+ */
+func (idx *methodIndex) add(t types.Type) bool {
+    if types.IsInterface(t) {
+        return false
+    }
+    if _, isParam := t.(*types.TypeParam); isParam {
+        return false
+    }
+    base := t
+    if ptr, ok := t.(*types.Pointer); ok {
+        base = ptr.Elem()
+    }
+    if named, ok := base.(*types.Named); ok &&
+        named.TypeParams().Len() > 0 && named.TypeArgs().Len() == 0 {
+        return false
+    }
+
+    added := false
+    for _, cand := range []types.Type{base, types.NewPointer(base)} {
+        if idx.seen.At(cand) != nil {
+            continue
+        }
+        idx.seen.Set(cand, true)
+        added = true
+
+        mset := idx.prog.MethodSets.MethodSet(cand)
+        for i := 0; i < mset.Len(); i++ {
+            name := mset.At(i).Obj().Name()
+            idx.byName[name] = append(idx.byName[name], cand)
+        }
+    }
+    return added
+}
+
+/* ============================================================================
+ * implementations
+ * ----------------------------------------------------------------------------
+ * Returns the declared concrete methods that an interface method may dispatch
+ * to, given the indexed candidate types. Promoted methods resolve to the
+ * method on the embedded type rather than to SSA's synthetic wrapper, so the
+ * edge lands on a function that has a body and a package.
+ * ============================================================================
+ */
+func (idx *methodIndex) implementations(m *types.Func) []*ssa.Function {
+    sig, ok := m.Type().(*types.Signature)
+    if !ok || sig.Recv() == nil {
+        return nil
+    }
+    iface, ok := sig.Recv().Type().Underlying().(*types.Interface)
+    if !ok {
+        return nil
+    }
+
+    var result []*ssa.Function
+    dedup := map[*ssa.Function]struct{}{}
+    for _, cand := range idx.byName[m.Name()] {
+        if !types.Implements(cand, iface) {
+            continue
+        }
+        sel := idx.prog.MethodSets.MethodSet(cand).Lookup(m.Pkg(), m.Name())
+        if sel == nil {
+            continue
+        }
+        obj, ok := sel.Obj().(*types.Func)
+        if !ok {
+            continue
+        }
+        fn := resolveServiceableFunc(idx.prog.FuncValue(obj), idx.mode)
+        if fn == nil {
+            continue
+        }
+        if _, dup := dedup[fn]; dup {
+            continue
+        }
+        dedup[fn] = struct{}{}
+        result = append(result, fn)
+    }
+    return result
+}
+
+/* ============================================================================
+ * declaredTypes
+ * ----------------------------------------------------------------------------
+ * Returns every named type declared at package level in pkg. Used as the
+ * CHA candidate set.
+ * ============================================================================
+ */
+func declaredTypes(pkg *ssa.Package) []types.Type {
+    var result []types.Type
+    for _, mem := range pkg.Members {
+        if t, ok := mem.(*ssa.Type); ok {
+            result = append(result, t.Type())
+        }
+    }
+    return result
+}
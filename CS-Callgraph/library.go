@@ -0,0 +1,98 @@
+package cs_callgraph
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+/* ============================================================================
+ * FoundLibrary
+ * ----------------------------------------------------------------------------
+ * The public API of the selected packages, used in place of a main entry
+ * point when analysing a library.
+ *
+ *   Packages  selected packages - the depth map is rooted at these
+ *   Funcs     every exported function and exported method of an exported
+ *             type declared in those packages
+ * ============================================================================
+ */
+type FoundLibrary struct {
+    Packages []*ssa.Package
+    Funcs    []*ssa.Function
+}
+
+/* ============================================================================
+ * ResolveLibrary
+ * ----------------------------------------------------------------------------
+ * Collects the public API of every package whose path is in selected.
+ * Both value and pointer method sets of each exported type are included, so
+ * methods with pointer receivers count as API as well. Fails if nothing is
+ * selected, mirroring ResolveMain. Progress is written to log.
+ * ============================================================================
+ */
+func ResolveLibrary(prog *ssa.Program, selected map[string]struct{}, log io.Writer) (*FoundLibrary, error) {
+    lib  := &FoundLibrary{}
+    seen := map[*ssa.Function]bool{}
+
+    add := func(fn *ssa.Function) {
+        if fn == nil || seen[fn] {
+            return
+        }
+        seen[fn] = true
+        lib.Funcs = append(lib.Funcs, fn)
+    }
+
+    for _, pkg := range prog.AllPackages() {
+        if pkg.Pkg == nil {
+            continue
+        }
+        if _, ok := selected[pkg.Pkg.Path()]; !ok {
+            continue
+        }
+        lib.Packages = append(lib.Packages, pkg)
+
+        for name, mem := range pkg.Members {
+            if !token.IsExported(name) {
+                continue
+            }
+            switch m := mem.(type) {
+            case *ssa.Function:
+                add(m)
+            case *ssa.Type:
+                for _, t := range []types.Type{m.Type(), types.NewPointer(m.Type())} {
+                    mset := prog.MethodSets.MethodSet(t)
+                    for i := 0; i < mset.Len(); i++ {
+                        obj, ok := mset.At(i).Obj().(*types.Func)
+                        if !ok || !obj.Exported() {
+                            continue
+                        }
+                        add(prog.FuncValue(obj))
+                    }
+                }
+            }
+        }
+    }
+
+    if len(lib.Packages) == 0 {
+        return nil, fmt.Errorf("no packages selected for library mode")
+    }
+
+    sort.Slice(lib.Packages, func(i, j int) bool {
+        return lib.Packages[i].Pkg.Path() < lib.Packages[j].Pkg.Path()
+    })
+    sort.Slice(lib.Funcs, func(i, j int) bool {
+        return lib.Funcs[i].String() < lib.Funcs[j].String()
+    })
+
+    fmt.Fprintf(log, "[ResolveLibrary] %d package(s), %d exported function(s)\n",
+        len(lib.Packages), len(lib.Funcs))
+    for _, pkg := range lib.Packages {
+        fmt.Fprintf(log, "  -> %s\n", pkg.Pkg.Path())
+    }
+    return lib, nil
+}
@@ -0,0 +1,254 @@
+package cs_callgraph
+
+import (
+	"fmt"
+	"sort"
+)
+
+/* ============================================================================
+ * FindFunction
+ * ----------------------------------------------------------------------------
+ * Returns the node of the function or interface method named name, in the
+ * fully qualified form the -main flag accepts ("github.com/you/repo.Run")
+ * or, for methods, as printed by SSA ("(*github.com/you/repo.T).Run").
+ * On a context-expanded graph this is the empty-context node.
+ * ============================================================================
+ */
+func (g *Graph) FindFunction(name string) (*Node, error) {
+    for fn, n := range g.Nodes {
+        if fn != nil && fn.String() == name {
+            return n, nil
+        }
+    }
+    for m, n := range g.IfaceNodes {
+        if m.FullName() == name {
+            return n, nil
+        }
+    }
+    return nil, fmt.Errorf("function %q not found in the call graph", name)
+}
+
+/* ============================================================================
+ * QualifiedName
+ * ----------------------------------------------------------------------------
+ * Returns the name FindFunction accepts for n, plus the call-site context
+ * on expanded graphs. The panic sink is "PANIC", the synthetic root "<root>".
+ * ============================================================================
+ */
+func (n *Node) QualifiedName() string {
+    switch {
+    case n.IfaceMethod != nil:
+        return n.IfaceMethod.FullName() + n.ContextString()
+    case n.Func != nil:
+        return n.Func.String() + n.ContextString()
+    case n.ID == -99:
+        return "PANIC"
+    default:
+        return "<root>"
+    }
+}
+
+/* ============================================================================
+ * Hop
+ * ----------------------------------------------------------------------------
+ * One function found by Callers / Callees.
+ *
+ *   Node   the caller / callee
+ *   Depth  number of edges from the queried function (1 = direct)
+ *   Edge   the edge it was first reached over; for callers it leads from
+ *          Node, for callees into Node
+ * ============================================================================
+ */
+type Hop struct {
+    Node  *Node
+    Depth int
+    Edge  *Edge
+}
+
+/* ============================================================================
+ * isQueryEdge
+ * ----------------------------------------------------------------------------
+ * Reports whether queries traverse e: the call-like edges (see isCallLike)
+ * plus the dispatch from an interface method to its implementations. A
+ * function value merely referenced (AssignEdge, SendEdge) is not called
+ * there; the synthetic root, the panic sink and the instance → origin
+ * bookkeeping edges are not calls either.
+ * ============================================================================
+ */
+func isQueryEdge(e *Edge) bool {
+    return isCallLike(e.Kind) || e.Kind == DispatchEdge
+}
+
+/* ============================================================================
+ * Callers / Callees
+ * ----------------------------------------------------------------------------
+ * Breadth-first search against / along the edges from n, up to levels
+ * edges away (-1 = unlimited). Every function is reported once, at its
+ * smallest depth; results are sorted by depth, then name. n itself is
+ * only listed if it is reached through recursion.
+ * ============================================================================
+ */
+func Callers(n *Node, levels int) []Hop {
+    return walk(n, levels, true)
+}
+
+func Callees(n *Node, levels int) []Hop {
+    return walk(n, levels, false)
+}
+
+func walk(start *Node, levels int, backward bool) []Hop {
+    var result []Hop
+    seen     := map[*Node]bool{}
+    frontier := []*Node{start}
+
+    for depth := 1; len(frontier) > 0 && (levels < 0 || depth <= levels); depth++ {
+        var next []*Node
+        for _, n := range frontier {
+            edges := n.Out
+            if backward {
+                edges = n.In
+            }
+            for _, e := range edges {
+                if !isQueryEdge(e) {
+                    continue
+                }
+                other := e.Callee
+                if backward {
+                    other = e.Caller
+                }
+                if seen[other] {
+                    continue
+                }
+                seen[other] = true
+                result = append(result, Hop{Node: other, Depth: depth, Edge: e})
+                next = append(next, other)
+            }
+        }
+        frontier = next
+    }
+
+    sort.SliceStable(result, func(i, j int) bool {
+        if result[i].Depth != result[j].Depth {
+            return result[i].Depth < result[j].Depth
+        }
+        return result[i].Node.QualifiedName() < result[j].Node.QualifiedName()
+    })
+    return result
+}
+
+type nodePair struct{ from, to *Node }
+
+/* ============================================================================
+ * ShortestPaths
+ * ----------------------------------------------------------------------------
+ * Returns up to k loop-free call paths from one node to another, shortest
+ * first (Yen's algorithm over breadth-first searches). Paths are distinct
+ * as node sequences; where several edges join the same two functions, the
+ * first one found stands for all of them. A path from a node to itself is
+ * empty. Returns nil if to is unreachable.
+ * ============================================================================
+ */
+func ShortestPaths(from, to *Node, k int) [][]*Edge {
+    if from == to {
+        return [][]*Edge{{}}
+    }
+    first := shortestPath(from, to, nil, nil)
+    if first == nil || k < 1 {
+        return nil
+    }
+
+    paths      := [][]*Edge{first}
+    var candidates [][]*Edge
+    for len(paths) < k {
+        prev := paths[len(paths)-1]
+        for j := range prev {
+            spur := prev[j].Caller
+            root := prev[:j]
+
+            blockedPairs := map[nodePair]bool{}
+            for _, p := range paths {
+                if len(p) > j && samePrefix(p, root) {
+                    blockedPairs[nodePair{p[j].Caller, p[j].Callee}] = true
+                }
+            }
+            blockedNodes := map[*Node]bool{}
+            for _, e := range root {
+                blockedNodes[e.Caller] = true
+            }
+
+            tail := shortestPath(spur, to, blockedNodes, blockedPairs)
+            if tail == nil {
+                continue
+            }
+            candidate := append(append([]*Edge{}, root...), tail...)
+            if !containsPath(paths, candidate) && !containsPath(candidates, candidate) {
+                candidates = append(candidates, candidate)
+            }
+        }
+        if len(candidates) == 0 {
+            break
+        }
+
+        best := 0
+        for i, c := range candidates {
+            if len(c) < len(candidates[best]) {
+                best = i
+            }
+        }
+        paths      = append(paths, candidates[best])
+        candidates = append(candidates[:best], candidates[best+1:]...)
+    }
+    return paths
+}
+
+func shortestPath(
+    from, to     *Node,
+    blockedNodes map[*Node]bool,
+    blockedPairs map[nodePair]bool,
+) []*Edge {
+    via      := map[*Node]*Edge{}
+    seen     := map[*Node]bool{from: true}
+    frontier := []*Node{from}
+
+    for len(frontier) > 0 {
+        var next []*Node
+        for _, n := range frontier {
+            for _, e := range n.Out {
+                c := e.Callee
+                if !isQueryEdge(e) || seen[c] || blockedNodes[c] || blockedPairs[nodePair{n, c}] {
+                    continue
+                }
+                seen[c] = true
+                via[c]  = e
+                if c == to {
+                    var path []*Edge
+                    for at := to; at != from; at = via[at].Caller {
+                        path = append([]*Edge{via[at]}, path...)
+                    }
+                    return path
+                }
+                next = append(next, c)
+            }
+        }
+        frontier = next
+    }
+    return nil
+}
+
+func samePrefix(path, prefix []*Edge) bool {
+    for i, e := range prefix {
+        if path[i].Caller != e.Caller || path[i].Callee != e.Callee {
+            return false
+        }
+    }
+    return true
+}
+
+func containsPath(paths [][]*Edge, path []*Edge) bool {
+    for _, p := range paths {
+        if len(p) == len(path) && samePrefix(p, path) {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,269 @@
+package cs_callgraph
+
+import (
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+/* ============================================================================
+ * Reflective dispatch
+ * ----------------------------------------------------------------------------
+ * Calls made through package reflect are ordinary static calls into
+ * reflect.Value, so extractEdges only sees an edge into package reflect.
+ * reflectState collects what is needed to recover the real targets:
+ *
+ *   valueOfs  every reflect.ValueOf call in a scanned function
+ *   sites     every reflect.Value.Call / CallSlice / Method / MethodByName
+ *             call in a scanned function
+ *
+ * resolveReflectCalls then links each site to a conservative target set:
+ * every exported method of every type that flows into reflect.ValueOf, plus
+ * (for Call / CallSlice) every function value that does.
+ * ============================================================================
+ */
+type reflectState struct {
+    genMode  GenericsMode
+    valueOfs []ssa.CallInstruction
+    sites    []reflectSite
+}
+
+type reflectSite struct {
+    caller *Node
+    site   ssa.CallInstruction
+}
+
+// Methods of reflect.Value that invoke (or look up for invocation) a
+// function the program never names directly.
+var reflectDispatchMethods = map[string]bool{
+    "Call":         true,
+    "CallSlice":    true,
+    "Method":       true,
+    "MethodByName": true,
+}
+
+// Levels of parameter passing followed back from a reflect.ValueOf argument
+const reflectChaseDepth = 3
+
+/* ============================================================================
+ * IsReflectDispatch
+ * ----------------------------------------------------------------------------
+ * Reports whether call is reflect.Value.Call, CallSlice, Method or
+ * MethodByName.
+ * ============================================================================
+ */
+func IsReflectDispatch(call *ssa.CallCommon) bool {
+    fn := call.StaticCallee()
+    if fn == nil || fn.Pkg == nil || fn.Pkg.Pkg.Path() != "reflect" {
+        return false
+    }
+    recv := fn.Signature.Recv()
+    if recv == nil || !isReflectValue(recv.Type()) {
+        return false
+    }
+    return reflectDispatchMethods[fn.Name()]
+}
+
+func isReflectValueOf(call *ssa.CallCommon) bool {
+    fn := call.StaticCallee()
+    return fn != nil && fn.Pkg != nil && fn.Pkg.Pkg.Path() == "reflect" &&
+        fn.Signature.Recv() == nil && fn.Name() == "ValueOf"
+}
+
+func isReflectValue(t types.Type) bool {
+    if p, ok := t.(*types.Pointer); ok {
+        t = p.Elem()
+    }
+    named, ok := t.(*types.Named)
+    return ok && named.Obj().Pkg() != nil &&
+        named.Obj().Pkg().Path() == "reflect" && named.Obj().Name() == "Value"
+}
+
+/* ============================================================================
+ * observe
+ * ----------------------------------------------------------------------------
+ * Records instr if it is a reflect.ValueOf call or a reflective dispatch
+ * site. Called by visit for every instruction of a scanned function.
+ * Package reflect itself is ignored - its internals call Method and Call on
+ * values the program never handed to it.
+ * ============================================================================
+ */
+func (rs *reflectState) observe(caller *Node, instr ssa.Instruction) {
+    call, ok := instr.(ssa.CallInstruction)
+    if !ok {
+        return
+    }
+    if pkg := EffectivePkg(instr.Parent()); pkg != nil && pkg.Pkg.Path() == "reflect" {
+        return
+    }
+    switch {
+    case isReflectValueOf(call.Common()):
+        rs.valueOfs = append(rs.valueOfs, call)
+    case IsReflectDispatch(call.Common()):
+        rs.sites = append(rs.sites, reflectSite{caller: caller, site: call})
+    }
+}
+
+/* ============================================================================
+ * resolveReflectCalls
+ * ----------------------------------------------------------------------------
+ * Adds a ReflectEdge from every reflective dispatch site to each target
+ * (see reflectState). Values reaching reflect.ValueOf through a parameter
+ * are chased back through the parameter's callers in cg, so a registry such
+ * as Register(svc any) { reflect.ValueOf(svc) } still sees the concrete
+ * types passed to it.
+ *
+ * Targets in unknown packages are dropped, as in resolveIfaceDispatch.
+ * Returns the targets that received a new edge so the caller can visit them.
+ * ============================================================================
+ */
+func resolveReflectCalls(
+    prog          *ssa.Program,
+    cg            *Graph,
+    rs            *reflectState,
+    existingEdges map[edgeKey]*Edge,
+    pkgStatus     func(string) (bool, bool),
+) []*ssa.Function {
+    if len(rs.sites) == 0 {
+        return nil
+    }
+
+    var (
+        flowTypes typeutil.Map
+        flowFuncs = map[*ssa.Function]bool{}
+    )
+    for _, call := range rs.valueOfs {
+        args := call.Common().Args
+        if len(args) == 1 {
+            collectReflectFlow(cg, args[0], reflectChaseDepth, rs.genMode, &flowTypes, flowFuncs)
+        }
+    }
+
+    methodSet := map[*ssa.Function]bool{}
+    flowTypes.Iterate(func(t types.Type, _ any) {
+        mset := prog.MethodSets.MethodSet(t)
+        for i := 0; i < mset.Len(); i++ {
+            sel := mset.At(i)
+            if !sel.Obj().Exported() {
+                continue
+            }
+            if fn := prog.MethodValue(sel); fn != nil {
+                methodSet[resolveServiceableFunc(fn, rs.genMode)] = true
+            }
+        }
+    })
+    methods := sortedFuncs(methodSet)
+    funcs   := sortedFuncs(flowFuncs)
+
+    var targets []*ssa.Function
+    for _, s := range rs.sites {
+        candidates := methods
+        if name := s.site.Common().StaticCallee().Name(); name == "Call" || name == "CallSlice" {
+            candidates = append(append([]*ssa.Function{}, methods...), funcs...)
+        }
+
+        for _, target := range candidates {
+            pkg := EffectivePkg(target)
+            if pkg == nil || pkg.Pkg == nil {
+                continue
+            }
+            if known, _ := pkgStatus(pkg.Pkg.Path()); !known {
+                continue
+            }
+
+            callee := cg.GenNode(target)
+            key    := edgeKey{from: s.caller, to: callee, kind: ReflectEdge, prov: ProvSyntactic}
+            if edge, exists := existingEdges[key]; exists {
+                if !containsSite(edge.Sites, s.site) {
+                    edge.Sites = append(edge.Sites, s.site)
+                }
+                continue
+            }
+            existingEdges[key] = GenEdge(s.caller, s.site, callee, ReflectEdge)
+            targets = append(targets, target)
+        }
+    }
+    return targets
+}
+
+func sortedFuncs(set map[*ssa.Function]bool) []*ssa.Function {
+    fns := make([]*ssa.Function, 0, len(set))
+    for fn := range set {
+        fns = append(fns, fn)
+    }
+    sort.Slice(fns, func(i, j int) bool {
+        return fns[i].String() < fns[j].String()
+    })
+    return fns
+}
+
+/* ============================================================================
+ * collectReflectFlow
+ * ----------------------------------------------------------------------------
+ * Records the dynamic types (and function values) v may hold when it is
+ * passed to reflect.ValueOf. Parameters are replaced by the matching
+ * argument at each call, go, defer or interface call site of their function
+ * (for a method, also the invoke sites of its interface method), up to
+ * depth levels.
+ * ============================================================================
+ */
+func collectReflectFlow(
+    cg        *Graph,
+    v         ssa.Value,
+    depth     int,
+    mode      GenericsMode,
+    flowTypes *typeutil.Map,
+    funcs     map[*ssa.Function]bool,
+) {
+    switch v := v.(type) {
+    case *ssa.MakeInterface:
+        if fn, ok := isFuncValue(v.X, mode); ok {
+            funcs[fn] = true
+            return
+        }
+        flowTypes.Set(v.X.Type(), true)
+
+    case *ssa.ChangeInterface:
+        collectReflectFlow(cg, v.X, depth, mode, flowTypes, funcs)
+
+    case *ssa.Parameter:
+        if depth <= 0 {
+            return
+        }
+        fnNode := cg.Nodes[v.Parent()]
+        if fnNode == nil {
+            return
+        }
+        // A method reached through an interface is called at the invoke
+        // sites of its interface node, one dispatch edge further up.
+        in := append([]*Edge{}, fnNode.In...)
+        for _, e := range fnNode.In {
+            if e.Kind == DispatchEdge && e.Caller != nil {
+                in = append(in, e.Caller.In...)
+            }
+        }
+        for _, e := range in {
+            switch e.Kind {
+            case CallEdge, GoEdge, DeferEdge, InterfaceEdge:
+            default:
+                continue // the sites of other edges do not pass v's arguments
+            }
+            for _, site := range e.Sites {
+                call, ok := site.(ssa.CallInstruction)
+                if !ok {
+                    continue
+                }
+                common := call.Common()
+                idx    := paramIndex(v)
+                if common.IsInvoke() {
+                    idx-- // Args exclude the receiver, Params of the method do not
+                }
+                if idx >= 0 && idx < len(common.Args) {
+                    collectReflectFlow(cg, common.Args[idx], depth-1, mode, flowTypes, funcs)
+                }
+            }
+        }
+    }
+}
@@ -0,0 +1,94 @@
+package cs_callgraph
+
+import "sort"
+
+/* ============================================================================
+ * Cycles
+ * ----------------------------------------------------------------------------
+ * Returns the non-trivial strongly connected components of g: sets of two or
+ * more functions that (mutually) call each other, plus single functions
+ * calling themselves. Uses Tarjan's algorithm over the edges that transfer
+ * control (see isCallLike), so references (assign, send), dispatch links,
+ * the synthetic root, the panic sink and instance → origin links never
+ * close a cycle.
+ *
+ * Members of a component are sorted by QualifiedName; components are sorted
+ * largest first, then by their first member.
+ * ============================================================================
+ */
+func (g *Graph) Cycles() [][]*Node {
+    t := &tarjan{
+        index:   make(map[*Node]int),
+        lowlink: make(map[*Node]int),
+        onStack: make(map[*Node]bool),
+    }
+    for _, n := range append(g.FunctionNodes(), g.InterfaceNodes()...) {
+        if _, visited := t.index[n]; !visited {
+            t.connect(n)
+        }
+    }
+
+    for _, scc := range t.sccs {
+        sort.Slice(scc, func(i, j int) bool {
+            return scc[i].QualifiedName() < scc[j].QualifiedName()
+        })
+    }
+    sort.SliceStable(t.sccs, func(i, j int) bool {
+        if len(t.sccs[i]) != len(t.sccs[j]) {
+            return len(t.sccs[i]) > len(t.sccs[j])
+        }
+        return t.sccs[i][0].QualifiedName() < t.sccs[j][0].QualifiedName()
+    })
+    return t.sccs
+}
+
+type tarjan struct {
+    next    int
+    index   map[*Node]int
+    lowlink map[*Node]int
+    onStack map[*Node]bool
+    stack   []*Node
+    sccs    [][]*Node
+}
+
+func (t *tarjan) connect(n *Node) {
+    t.index[n]   = t.next
+    t.lowlink[n] = t.next
+    t.next++
+    t.stack      = append(t.stack, n)
+    t.onStack[n] = true
+
+    selfLoop := false
+    for _, e := range n.Out {
+        if !isCallLike(e.Kind) {
+            continue
+        }
+        c := e.Callee
+        if c == n {
+            selfLoop = true
+        }
+        if _, visited := t.index[c]; !visited {
+            t.connect(c)
+            t.lowlink[n] = min(t.lowlink[n], t.lowlink[c])
+        } else if t.onStack[c] {
+            t.lowlink[n] = min(t.lowlink[n], t.index[c])
+        }
+    }
+    if t.lowlink[n] != t.index[n] {
+        return
+    }
+
+    var scc []*Node
+    for {
+        top := t.stack[len(t.stack)-1]
+        t.stack = t.stack[:len(t.stack)-1]
+        t.onStack[top] = false
+        scc = append(scc, top)
+        if top == n {
+            break
+        }
+    }
+    if len(scc) > 1 || selfLoop {
+        t.sccs = append(t.sccs, scc)
+    }
+}
@@ -0,0 +1,74 @@
+package cs_callgraph_test
+
+import (
+    "reflect"
+    "testing"
+
+    "callstat/CS-Callgraph/cgtest"
+)
+
+func TestCycles(t *testing.T) {
+    tests := []struct {
+        name  string
+        specs []string
+        want  [][]string
+    }{
+        {
+            name:  "acyclic",
+            specs: []string{"ex.A -> ex.B", "ex.B -> ex.C", "ex.A -> ex.C"},
+            want:  [][]string{},
+        },
+        {
+            name:  "self-loop",
+            specs: []string{"ex.A -> ex.A", "ex.A -> ex.B"},
+            want:  [][]string{{"ex.A"}},
+        },
+        {
+            name:  "mutual recursion",
+            specs: []string{"ex.Main -> ex.Even", "ex.Even -> ex.Odd", "ex.Odd -> ex.Even"},
+            want:  [][]string{{"ex.Even", "ex.Odd"}},
+        },
+        {
+            // An inner 2-cycle inside an outer 4-cycle is a single component;
+            // a separate self-loop downstream stays its own.
+            name: "nested",
+            specs: []string{
+                "ex.A -> ex.B", "ex.B -> ex.C", "ex.C -> ex.B",
+                "ex.C -> ex.D", "ex.D -> ex.A",
+                "ex.D -> ex.E", "ex.E -> ex.E",
+            },
+            want: [][]string{{"ex.A", "ex.B", "ex.C", "ex.D"}, {"ex.E"}},
+        },
+        {
+            name: "ordered largest first, then by first member",
+            specs: []string{
+                "ex/b.X -> ex/b.Y", "ex/b.Y -> ex/b.X",
+                "ex/a.P -> ex/a.Q", "ex/a.Q -> ex/a.P",
+                "ex/c.R -> ex/c.S", "ex/c.S -> ex/c.T", "ex/c.T -> ex/c.R",
+            },
+            want: [][]string{{"ex/c.R", "ex/c.S", "ex/c.T"}, {"ex/a.P", "ex/a.Q"}, {"ex/b.X", "ex/b.Y"}},
+        },
+        {
+            name:  "instance and panic edges do not close a cycle",
+            specs: []string{"ex.A -> ex.B", "ex.B -instance-> ex.A", "ex.C -panic-> ex.C"},
+            want:  [][]string{},
+        },
+        {
+            name:  "assign edge does not close a cycle",
+            specs: []string{"ex.A -assign-> ex.B", "ex.B -> ex.A"},
+            want:  [][]string{},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            g, _ := cgtest.Build(t, tt.specs...)
+            got  := [][]string{}
+            for _, scc := range g.Cycles() {
+                got = append(got, cgtest.Names(scc))
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("Cycles() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,81 @@
+package cs_callgraph
+
+import "strings"
+
+/* ============================================================================
+ * MatchesPattern
+ * ----------------------------------------------------------------------------
+ * Reports whether pkgPath matches any pattern in the list.
+ *
+ * Two match modes:
+ *   "runtime"   exact match - only "runtime" itself
+ *   "runtime/"  prefix match - "runtime", "runtime/internal", etc.
+ * ============================================================================
+ */
+func MatchesPattern(pkgPath string, patterns []string) bool {
+    for _, p := range patterns {
+        if strings.HasSuffix(p, "/") {
+            base := strings.TrimSuffix(p, "/")
+            if pkgPath == base || strings.HasPrefix(pkgPath, base+"/") {
+                return true
+            }
+        } else if pkgPath == p {
+            return true
+        }
+    }
+    return false
+}
+
+/* ============================================================================
+ * MarkSinks
+ * ----------------------------------------------------------------------------
+ * Sets Node.Sink on every function and interface method node matching one
+ * of the patterns and returns those nodes, sorted by ID. A pattern matches
+ * either a fully qualified function name ("os/exec.Command",
+ * "(*os/exec.Cmd).Run") or the node's package as in MatchesPattern
+ * ("syscall", "internal/legacy/").
+ *
+ * Only functions that have a node can match: with -no-stdlib or -skip-cg
+ * the excluded packages never do. Call before ExpandContexts so the
+ * expanded nodes inherit the mark.
+ * ============================================================================
+ */
+func MarkSinks(g *Graph, patterns []string) []*Node {
+    if len(patterns) == 0 {
+        return nil
+    }
+    isSink := func(name, pkgPath string) bool {
+        for _, p := range patterns {
+            if p == name {
+                return true
+            }
+        }
+        return MatchesPattern(pkgPath, patterns)
+    }
+
+    var sinks []*Node
+    for _, n := range g.FunctionNodes() {
+        if n.Func == nil {
+            continue
+        }
+        pkgPath := ""
+        if pkg := EffectivePkg(n.Func); pkg != nil && pkg.Pkg != nil {
+            pkgPath = pkg.Pkg.Path()
+        }
+        if isSink(n.Func.String(), pkgPath) {
+            n.Sink = true
+            sinks   = append(sinks, n)
+        }
+    }
+    for _, n := range g.InterfaceNodes() {
+        pkgPath := ""
+        if n.IfaceMethod.Pkg() != nil {
+            pkgPath = n.IfaceMethod.Pkg().Path()
+        }
+        if isSink(n.IfaceMethod.FullName(), pkgPath) {
+            n.Sink = true
+            sinks   = append(sinks, n)
+        }
+    }
+    return sinks
+}
@@ -1,20 +1,36 @@
 package cs_callgraph
 
-import "golang.org/x/tools/go/packages"
+import (
+	"context"
+	"fmt"
 
-var stdPackages = map[string]struct{}{}
+	"golang.org/x/tools/go/packages"
+)
 
-func InitSTDLib() {
-    pkgs, err := packages.Load(nil, "std")
+/* ============================================================================
+ * Standard library detection
+ * ----------------------------------------------------------------------------
+ * STDLib is the set of std package paths, loaded once per analysis by
+ * LoadSTDLib and read-only afterwards. Analyze keeps it on the graphs it
+ * builds (Graph.Std), so later stages need no setup of their own. A nil set
+ * reports no package as std.
+ * ============================================================================
+ */
+type STDLib map[string]struct{}
+
+func LoadSTDLib(ctx context.Context) (STDLib, error) {
+    pkgs, err := packages.Load(&packages.Config{Context: ctx}, "std")
     if err != nil {
-        panic(err)
+        return nil, fmt.Errorf("loading standard library packages: %w", err)
     }
+    std := make(STDLib, len(pkgs))
     for _, p := range pkgs {
-        stdPackages[p.PkgPath] = struct{}{}
+        std[p.PkgPath] = struct{}{}
     }
+    return std, nil
 }
 
-func IsStdlib(pkgPath string) bool {
-    _, ok := stdPackages[pkgPath]
+func (s STDLib) Contains(pkgPath string) bool {
+    _, ok := s[pkgPath]
     return ok
-}
\ No newline at end of file
+}
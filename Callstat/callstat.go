@@ -0,0 +1,382 @@
+package callstat
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"strings"
+	"time"
+
+	cs_callgraph "callstat/CS-Callgraph"
+	rules "callstat/Rules"
+	stats "callstat/Statistics"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+/* ============================================================================
+ * Config
+ * ----------------------------------------------------------------------------
+ * Mirrors the analysis flags of the CLI. Output locations (report, DOT/SVG
+ * folders, stats JSON) are not part of it - Analyze only produces data; the
+ * caller decides what to write.
+ *
+ *   Dir        project to analyse
+ *   Depth      external package depth (-1 = unlimited)
+ *   NoStdlib   exclude the standard library from the call graph
+ *   SkipCG     packages excluded from the call graph (trailing / = prefix)
+ *   Main       explicit main, e.g. "github.com/you/repo/cmd/serve.main"
+ *   AllMains   use every main in the project
+ *   Inits      also use every in-depth package init
+ *   Entries    additional fully qualified entry functions
+ *   Lib        library mode - exported API of LibPkgs is the root set
+ *   LibPkgs    library packages (defaults to the whole project)
+ *   Iface      interface dispatch resolution
+ *   FuncValue  function value call resolution
+ *   Generics   one node per generic function, or per instantiation
+ *   K          k-CFA context depth (0 = off); calls through function
+ *              values only get edges to split with FuncValue = FuncValVTA
+ *   View       "collapsed" or "expanded" - graph the report describes
+ *   Sinks      sink functions or packages (see cs_callgraph.MarkSinks);
+ *              the report then lists the entries reaching each of them
+ *   Centrality compute per-function centrality over the in-depth graph
+ *              (betweenness is O(V·E), so off by default)
+ *   FuncMetrics add per-function code metrics (blocks, instructions,
+ *              cyclomatic complexity, line span, …) to the report
+ *   Rules      architecture rules to check against the collapsed graph
+ *              (see rules.Load); nil = none
+ *   NoStats    skip building the CallGraphReport
+ *   Log        progress messages (selected main, depth map roots, …);
+ *              nil discards them
+ *
+ * Use DefaultConfig for the CLI defaults; the zero value is not useful.
+ * ============================================================================
+ */
+type Config struct {
+    Dir        string
+    Depth      int
+    NoStdlib   bool
+    SkipCG     []string
+    Main       string
+    AllMains   bool
+    Inits      bool
+    Entries    []string
+    Lib        bool
+    LibPkgs    []string
+    Iface      cs_callgraph.IfaceMode
+    FuncValue  cs_callgraph.FuncValueMode
+    Generics   cs_callgraph.GenericsMode
+    K          int
+    View       string
+    Sinks      []string
+    Centrality bool
+    FuncMetrics bool
+    Rules      *rules.RuleSet
+    NoStats    bool
+    Log        io.Writer
+}
+
+func DefaultConfig(dir string) Config {
+    return Config{
+        Dir:       dir,
+        Depth:     2,
+        Iface:     cs_callgraph.IfaceCHA,
+        FuncValue: cs_callgraph.FuncValSyntactic,
+        View:      "collapsed",
+    }
+}
+
+/* ============================================================================
+ * Result
+ * ----------------------------------------------------------------------------
+ * Everything a single Analyze run produced.
+ *
+ *   Program       the SSA program the graphs refer to
+ *   ProjectRoot   module path of Config.Dir ("" if no go.mod was found)
+ *   PackagePaths  every loaded package path (input for MatchPackages)
+ *   Modules       package path → owning module
+ *   DepthMap      package path → depth from the root packages
+ *   SkipCG        expanded Config.SkipCG / NoStdlib exclusions
+ *   Graph         collapsed call graph
+ *   Expanded      k-CFA graph, nil when K == 0
+ *   View          the graph Report describes (Graph or Expanded)
+ *   Centrality    metrics per in-depth node of View, nil unless
+ *                 Config.Centrality is set
+ *   Rules         outcome of Config.Rules, nil without rules
+ *   Report        call graph statistics, nil when NoStats is set
+ *   Timings       wall time per phase
+ * ============================================================================
+ */
+type Result struct {
+    Program      *ssa.Program
+    ProjectRoot  string
+    PackagePaths []string
+    Modules      map[string]*stats.ModuleRef
+    DepthMap     map[string]int
+    SkipCG       map[string]struct{}
+    Graph        *cs_callgraph.Graph
+    Expanded     *cs_callgraph.Graph
+    View         *cs_callgraph.Graph
+    Centrality   map[*cs_callgraph.Node]*cs_callgraph.Centrality
+    Rules        *rules.Report
+    Report       *stats.CallGraphReport
+    Timings      Timings
+}
+
+type Timings struct {
+    Load       time.Duration
+    SSA        time.Duration
+    CallGraph  time.Duration
+    Contexts   time.Duration
+    Centrality time.Duration
+    Rules      time.Duration
+    Stats      time.Duration
+}
+
+/* ============================================================================
+ * Analyze
+ * ----------------------------------------------------------------------------
+ * Runs the full pipeline - package load, SSA build, root resolution, call
+ * graph, optional context expansion and statistics - for cfg.
+ *
+ * ctx is checked between phases and passed to the package loader, so a
+ * cancelled context stops the run at the next phase boundary. Safe to call
+ * repeatedly and concurrently; each call builds its own program and writes
+ * only to cfg.Log.
+ * ============================================================================
+ */
+func Analyze(ctx context.Context, cfg Config) (res *Result, err error) {
+    if cfg.View == "" {
+        cfg.View = "collapsed"
+    }
+    if cfg.Log == nil {
+        cfg.Log = io.Discard
+    }
+    if cfg.View != "collapsed" && cfg.View != "expanded" {
+        return nil, fmt.Errorf("unknown view %q (want collapsed or expanded)", cfg.View)
+    }
+    std, err := cs_callgraph.LoadSTDLib(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    res = &Result{ProjectRoot: ModuleName(cfg.Dir)}
+    defer func() {
+        if err != nil {
+            res = nil
+        }
+    }()
+
+    /* -------------------------------------------------------
+     * Load Packages + Build SSA
+     * ------------------------------------------------------- */
+    t := time.Now()
+    pkgs, err := packages.Load(&packages.Config{
+        Context: ctx,
+        Mode:    packages.LoadAllSyntax | packages.NeedModule,
+        Dir:     cfg.Dir,
+    }, "./...")
+    if err != nil {
+        return res, fmt.Errorf("load packages: %w", err)
+    }
+    res.Timings.Load = time.Since(t)
+    if err := ctx.Err(); err != nil {
+        return res, err
+    }
+
+    t = time.Now()
+    mode := ssa.BuilderMode(0)
+    if cfg.Generics == cs_callgraph.GenericsInstances {
+        mode |= ssa.InstantiateGenerics
+    }
+    prog, _ := ssautil.AllPackages(pkgs, mode)
+    prog.Build()
+    res.Program = prog
+    res.Timings.SSA = time.Since(t)
+    if err := ctx.Err(); err != nil {
+        return res, err
+    }
+
+    for _, pkg := range prog.AllPackages() {
+        if pkg.Pkg != nil {
+            res.PackagePaths = append(res.PackagePaths, pkg.Pkg.Path())
+        }
+    }
+    res.Modules = packageModules(pkgs, std)
+
+    /* -------------------------------------------------------
+     * Roots + Callgraph
+     * ------------------------------------------------------- */
+    t = time.Now()
+    roots, err := resolveRoots(prog, res.ProjectRoot, res.PackagePaths, cfg)
+    if err != nil {
+        return res, err
+    }
+    res.DepthMap = roots.depthMap
+    res.SkipCG   = MatchPackages(cfg.SkipCG, cfg.NoStdlib, std, res.PackagePaths)
+
+    cg := cs_callgraph.BuildExtendedCallGraph2(
+        prog, cfg.Depth, res.DepthMap, res.SkipCG, cfg.Iface, cfg.FuncValue, cfg.Generics, roots.extra,
+    )
+    entries := roots.entries
+    if cfg.Inits {
+        entries = append(entries, cs_callgraph.PackageInits(prog, func(path string) bool {
+            if _, skip := res.SkipCG[path]; skip {
+                return false
+            }
+            d, ok := res.DepthMap[path]
+            return ok && (cfg.Depth == -1 || d <= cfg.Depth)
+        })...)
+    }
+    cg.AttachRoot(entries)
+    cg.Std = std
+    cs_callgraph.MarkSinks(cg, cfg.Sinks)
+    res.Graph = cg
+    res.View  = cg
+    res.Timings.CallGraph = time.Since(t)
+    if err := ctx.Err(); err != nil {
+        return res, err
+    }
+
+    /* -------------------------------------------------------
+     * Context expansion (k-CFA)
+     * ------------------------------------------------------- */
+    if cfg.K > 0 {
+        t = time.Now()
+        res.Expanded = cs_callgraph.ExpandContexts(cg, cfg.K, cfg.Generics)
+        if cfg.View == "expanded" {
+            res.View = res.Expanded
+        }
+        res.Timings.Contexts = time.Since(t)
+        if err := ctx.Err(); err != nil {
+            return res, err
+        }
+    }
+
+    /* -------------------------------------------------------
+     * Centrality
+     * ------------------------------------------------------- */
+    if cfg.Centrality {
+        t = time.Now()
+        res.Centrality = cs_callgraph.ComputeCentrality(res.View, func(n *cs_callgraph.Node) bool {
+            path := cs_callgraph.NodePackage(n)
+            if _, skip := res.SkipCG[path]; skip {
+                return false
+            }
+            d, ok := res.DepthMap[path]
+            return ok && (cfg.Depth == -1 || d <= cfg.Depth)
+        })
+        res.Timings.Centrality = time.Since(t)
+        if err := ctx.Err(); err != nil {
+            return res, err
+        }
+    }
+
+    /* -------------------------------------------------------
+     * Architecture rules
+     * ------------------------------------------------------- */
+    if cfg.Rules != nil {
+        t = time.Now()
+        res.Rules = rules.Check(cg, cfg.Rules)
+        res.Timings.Rules = time.Since(t)
+    }
+
+    /* -------------------------------------------------------
+     * Statistics
+     * ------------------------------------------------------- */
+    if !cfg.NoStats {
+        t = time.Now()
+        report := stats.GatherCallGraphStats(
+            res.View, res.DepthMap, cfg.Depth, res.ProjectRoot, res.SkipCG,
+        )
+        report.Mode = roots.mode
+        report.Resolution = &stats.ResolutionInfo{
+            Iface:       cfg.Iface.String(),
+            FuncValue:   cfg.FuncValue.String(),
+            Generics:    cfg.Generics.String(),
+            BuildMillis: res.Timings.CallGraph.Milliseconds(),
+        }
+        report.Sinks = stats.GatherSinkReachability(res.View, cfg.Sinks)
+        report.Centrality = stats.CentralityTable(res.Centrality)
+        report.Rules = res.Rules
+        report.Modules = stats.GatherModuleUtilisation(
+            res.View, report, res.Modules, res.DepthMap, cfg.Depth, res.SkipCG,
+        )
+        if cfg.FuncMetrics {
+            stats.GatherFunctionMetrics(
+                res.View, report, prog.Fset, functionSpans(pkgs), res.DepthMap, cfg.Depth, res.SkipCG,
+            )
+        }
+        if cfg.K > 0 {
+            report.Contexts = stats.CompareContexts(cg, res.Expanded, res.View == res.Expanded)
+        }
+        res.Report = report
+        res.Timings.Stats = time.Since(t)
+    }
+    return res, nil
+}
+
+/* ============================================================================
+ * packageModules
+ * ----------------------------------------------------------------------------
+ * Maps every loaded package (including dependencies) to its module.
+ * Standard library packages have no module and are grouped as "std".
+ * ============================================================================
+ */
+func packageModules(pkgs []*packages.Package, stdlib cs_callgraph.STDLib) map[string]*stats.ModuleRef {
+    refs    := make(map[*packages.Module]*stats.ModuleRef)
+    std     := &stats.ModuleRef{Path: "std"}
+    modules := make(map[string]*stats.ModuleRef)
+
+    packages.Visit(pkgs, nil, func(p *packages.Package) {
+        m := p.Module
+        if m == nil {
+            if stdlib.Contains(p.PkgPath) {
+                modules[p.PkgPath] = std
+            }
+            return
+        }
+        ref, ok := refs[m]
+        if !ok {
+            ref = &stats.ModuleRef{Path: m.Path, Version: m.Version, Main: m.Main}
+            if m.Replace != nil {
+                ref.Replace = strings.TrimSpace(m.Replace.Path + " " + m.Replace.Version)
+            }
+            refs[m] = ref
+        }
+        modules[p.PkgPath] = ref
+    })
+    return modules
+}
+
+/* ============================================================================
+ * functionSpans
+ * ----------------------------------------------------------------------------
+ * Indexes the source extent of every function declaration and literal of
+ * the loaded packages by the position ssa.Function.Pos reports for it (the
+ * name of a FuncDecl, the func keyword of a FuncLit). The SSA functions
+ * drop their syntax once built, so the extents come from the ASTs.
+ * ============================================================================
+ */
+func functionSpans(pkgs []*packages.Package) map[token.Pos]stats.Span {
+    spans := make(map[token.Pos]stats.Span)
+
+    packages.Visit(pkgs, nil, func(p *packages.Package) {
+        for _, file := range p.Syntax {
+            ast.Inspect(file, func(node ast.Node) bool {
+                switch fn := node.(type) {
+                case *ast.FuncDecl:
+                    spans[fn.Name.Pos()] = stats.Span{Start: fn.Pos(), End: fn.End()}
+                case *ast.FuncLit:
+                    spans[fn.Type.Func] = stats.Span{Start: fn.Pos(), End: fn.End()}
+                }
+                return true
+            })
+        }
+    })
+    return spans
+}
@@ -0,0 +1,163 @@
+package callstat
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cs_callgraph "callstat/CS-Callgraph"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+/* ============================================================================
+ * rootSet
+ * ----------------------------------------------------------------------------
+ * The roots of one analysis.
+ *
+ *   depthMap  package depths, rooted at the entry packages
+ *   entries   functions the graph's Root fans out to
+ *   extra     functions that must be visited even if their package is not a
+ *             member scan target (library API, explicit -entry functions)
+ *   mode      "main", "all-mains" or "library", reported as CallGraphReport.Mode
+ * ============================================================================
+ */
+type rootSet struct {
+    depthMap map[string]int
+    entries  []*ssa.Function
+    extra    []*ssa.Function
+    mode     string
+}
+
+/* ============================================================================
+ * resolveRoots
+ * ----------------------------------------------------------------------------
+ * Picks the entry points for cfg: the public API in library mode, otherwise
+ * the selected main (or every main) plus any explicit Entries.
+ * ============================================================================
+ */
+func resolveRoots(
+    prog        *ssa.Program,
+    projectRoot string,
+    pkgPaths    []string,
+    cfg         Config,
+) (*rootSet, error) {
+    rs := &rootSet{}
+
+    if cfg.Lib {
+        patterns := cfg.LibPkgs
+        if len(patterns) == 0 {
+            patterns = []string{projectRoot + "/"}
+        }
+        lib, err := cs_callgraph.ResolveLibrary(prog, MatchPackages(patterns, false, nil, pkgPaths), cfg.Log)
+        if err != nil {
+            return nil, err
+        }
+        rs.depthMap, err = cs_callgraph.BuildPackageDepthMapFromRoots(prog, projectRoot, lib.Packages, cfg.Log)
+        if err != nil {
+            return nil, err
+        }
+        rs.entries = lib.Funcs
+        rs.extra   = lib.Funcs
+        rs.mode    = "library"
+        return rs, nil
+    }
+
+    var found []*cs_callgraph.FoundMain
+    if cfg.AllMains {
+        mains, err := cs_callgraph.ResolveAllMains(prog, projectRoot, cfg.Log)
+        if err != nil {
+            return nil, err
+        }
+        found   = mains
+        rs.mode = "all-mains"
+    } else {
+        m, err := cs_callgraph.ResolveMain(prog, projectRoot, cfg.Main, cfg.Log)
+        if err != nil {
+            return nil, err
+        }
+        found   = []*cs_callgraph.FoundMain{m}
+        rs.mode = "main"
+    }
+    for _, name := range cfg.Entries {
+        e, err := cs_callgraph.ResolveEntry(prog, name, cfg.Log)
+        if err != nil {
+            return nil, err
+        }
+        found    = append(found, e)
+        rs.extra = append(rs.extra, e.Funct)
+    }
+
+    var rootPkgs []*ssa.Package
+    seenPkg := map[*ssa.Package]bool{}
+    for _, f := range found {
+        rs.entries = append(rs.entries, f.Funct)
+        if !seenPkg[f.Packg] {
+            seenPkg[f.Packg] = true
+            rootPkgs = append(rootPkgs, f.Packg)
+        }
+    }
+
+    var err error
+    rs.depthMap, err = cs_callgraph.BuildPackageDepthMapFromRoots(prog, projectRoot, rootPkgs, cfg.Log)
+    if err != nil {
+        return nil, err
+    }
+    return rs, nil
+}
+
+/* ============================================================================
+ * ModuleName
+ * ----------------------------------------------------------------------------
+ * Climbs the directory tree from targetDir looking for a go.mod file.
+ * Returns the declared module name, or "" if none is found.
+ *
+ * Uses os.ReadFile to avoid a defer-inside-loop leak.
+ * ============================================================================
+ */
+func ModuleName(targetDir string) string {
+    absDir, err := filepath.Abs(targetDir)
+    if err != nil {
+        return ""
+    }
+    for curr := absDir; ; curr = filepath.Dir(curr) {
+        data, err := os.ReadFile(filepath.Join(curr, "go.mod"))
+        if err == nil {
+            scanner := bufio.NewScanner(strings.NewReader(string(data)))
+            if scanner.Scan() {
+                line := strings.TrimSpace(scanner.Text())
+                return strings.TrimPrefix(line, "module ")
+            }
+        }
+        if parent := filepath.Dir(curr); parent == curr {
+            break
+        }
+    }
+    return ""
+}
+
+/* ============================================================================
+ * MatchPackages
+ * ----------------------------------------------------------------------------
+ * Expands a list of patterns (and optionally all stdlib packages, as listed
+ * in stdlib) into a concrete map[pkgPath]struct{} by testing every known
+ * package path.
+ * ============================================================================
+ */
+func MatchPackages(
+    patterns      []string,
+    excludeStdlib bool,
+    stdlib        cs_callgraph.STDLib,
+    allPkgPaths   []string,
+) map[string]struct{} {
+    result := make(map[string]struct{})
+    for _, path := range allPkgPaths {
+        patternMatch := cs_callgraph.MatchesPattern(path, patterns)
+        stdlibMatch  := excludeStdlib && stdlib.Contains(path)
+        if patternMatch || stdlibMatch {
+            result[path] = struct{}{}
+        }
+    }
+    return result
+}
@@ -0,0 +1,232 @@
+package diff
+
+import (
+	"slices"
+	"sort"
+
+	export "callstat/Export"
+)
+
+/* ============================================================================
+ * Result
+ * ----------------------------------------------------------------------------
+ * The difference between two exports of the same project. Functions are
+ * matched by their fully qualified name (NodeRecord.Name), edges by caller
+ * name, callee name and kind, so node IDs never have to line up. Closures
+ * are named by their position among their parent's closures ("main$1") and
+ * context nodes by their call-site chain, so both can show up as removed
+ * and added when code above them moves.
+ *
+ *   Old / New       labels of the two sides (file or directory)
+ *   Summary         counts of everything below
+ *   AddedNodes      functions only in New
+ *   RemovedNodes    functions only in Old
+ *   AddedEdges      edges only in New
+ *   RemovedEdges    edges only in Old
+ *   NewlyReachable  functions reachable in New but absent or unreachable
+ *                   in Old
+ *   NewlyDead       functions reachable in Old that are still present in
+ *                   New but no longer reachable
+ * ============================================================================
+ */
+type Result struct {
+	Old            string        `json:"old"`
+	New            string        `json:"new"`
+	Summary        Summary       `json:"summary"`
+	AddedNodes     []*NodeChange `json:"addedNodes"`
+	RemovedNodes   []*NodeChange `json:"removedNodes"`
+	AddedEdges     []*EdgeChange `json:"addedEdges"`
+	RemovedEdges   []*EdgeChange `json:"removedEdges"`
+	NewlyReachable []*NodeChange `json:"newlyReachable"`
+	NewlyDead      []*NodeChange `json:"newlyDead"`
+}
+
+type Summary struct {
+	OldNodes       int `json:"oldNodes"`
+	NewNodes       int `json:"newNodes"`
+	OldEdges       int `json:"oldEdges"`
+	NewEdges       int `json:"newEdges"`
+	AddedNodes     int `json:"addedNodes"`
+	RemovedNodes   int `json:"removedNodes"`
+	AddedEdges     int `json:"addedEdges"`
+	RemovedEdges   int `json:"removedEdges"`
+	NewlyReachable int `json:"newlyReachable"`
+	NewlyDead      int `json:"newlyDead"`
+}
+
+/* ============================================================================
+ * NodeChange / EdgeChange
+ * ----------------------------------------------------------------------------
+ * A function or edge as recorded on the side it was found. Reachable is
+ * the function's reachability on that side; Positions are the call sites
+ * on that side.
+ * ============================================================================
+ */
+type NodeChange struct {
+	Name      string `json:"name"`
+	Package   string `json:"package"`
+	Kind      string `json:"kind"`
+	Reachable bool   `json:"reachable"`
+}
+
+type EdgeChange struct {
+	Caller        string            `json:"caller"`
+	CallerPackage string            `json:"callerPackage"`
+	Callee        string            `json:"callee"`
+	CalleePackage string            `json:"calleePackage"`
+	Kind          string            `json:"kind"`
+	Positions     []export.Position `json:"positions"`
+}
+
+// Identity of an edge across two exports
+type edgeKey struct {
+	caller, callee, kind string
+}
+
+/* ============================================================================
+ * side
+ * ----------------------------------------------------------------------------
+ * One export indexed by name. Records sharing a name (which Build does not
+ * produce, but hand-written files might) are merged, reachable if any is.
+ * ============================================================================
+ */
+type side struct {
+	nodes map[string]*NodeChange
+	edges map[edgeKey]*EdgeChange
+}
+
+func index(doc *export.Document) *side {
+	s := &side{
+		nodes: make(map[string]*NodeChange, len(doc.Nodes)),
+		edges: make(map[edgeKey]*EdgeChange, len(doc.Edges)),
+	}
+	byID := make(map[string]*export.NodeRecord, len(doc.Nodes))
+	for _, n := range doc.Nodes {
+		byID[n.ID] = n
+		reachable := slices.Contains(n.Flags, "reachable")
+		if prev, ok := s.nodes[n.Name]; ok {
+			prev.Reachable = prev.Reachable || reachable
+			continue
+		}
+		s.nodes[n.Name] = &NodeChange{Name: n.Name, Package: n.Package, Kind: n.Kind, Reachable: reachable}
+	}
+	for _, e := range doc.Edges {
+		caller, callee := byID[e.Source], byID[e.Target]
+		if caller == nil || callee == nil {
+			continue
+		}
+		key := edgeKey{caller.Name, callee.Name, e.Kind}
+		if prev, ok := s.edges[key]; ok {
+			prev.Positions = append(prev.Positions, e.Positions...)
+			continue
+		}
+		s.edges[key] = &EdgeChange{
+			Caller:        caller.Name,
+			CallerPackage: caller.Package,
+			Callee:        callee.Name,
+			CalleePackage: callee.Package,
+			Kind:          e.Kind,
+			Positions:     e.Positions,
+		}
+	}
+	return s
+}
+
+/* ============================================================================
+ * Compare
+ * ----------------------------------------------------------------------------
+ * Diffs two exports; oldName / newName only label the result. Every list
+ * is sorted by name (edges by caller, callee, kind).
+ * ============================================================================
+ */
+func Compare(oldDoc, newDoc *export.Document, oldName, newName string) *Result {
+	before, after := index(oldDoc), index(newDoc)
+
+	r := &Result{
+		Old:            oldName,
+		New:            newName,
+		AddedNodes:     []*NodeChange{},
+		RemovedNodes:   []*NodeChange{},
+		AddedEdges:     []*EdgeChange{},
+		RemovedEdges:   []*EdgeChange{},
+		NewlyReachable: []*NodeChange{},
+		NewlyDead:      []*NodeChange{},
+	}
+
+	for name, n := range after.nodes {
+		prev, existed := before.nodes[name]
+		if !existed {
+			r.AddedNodes = append(r.AddedNodes, n)
+		}
+		if n.Reachable && (!existed || !prev.Reachable) {
+			r.NewlyReachable = append(r.NewlyReachable, n)
+		}
+		if existed && prev.Reachable && !n.Reachable {
+			r.NewlyDead = append(r.NewlyDead, n)
+		}
+	}
+	for name, n := range before.nodes {
+		if _, ok := after.nodes[name]; !ok {
+			r.RemovedNodes = append(r.RemovedNodes, n)
+		}
+	}
+	for key, e := range after.edges {
+		if _, ok := before.edges[key]; !ok {
+			r.AddedEdges = append(r.AddedEdges, e)
+		}
+	}
+	for key, e := range before.edges {
+		if _, ok := after.edges[key]; !ok {
+			r.RemovedEdges = append(r.RemovedEdges, e)
+		}
+	}
+
+	for _, list := range [][]*NodeChange{r.AddedNodes, r.RemovedNodes, r.NewlyReachable, r.NewlyDead} {
+		sortNodes(list)
+	}
+	sortEdges(r.AddedEdges)
+	sortEdges(r.RemovedEdges)
+
+	r.Summary = Summary{
+		OldNodes:       len(before.nodes),
+		NewNodes:       len(after.nodes),
+		OldEdges:       len(before.edges),
+		NewEdges:       len(after.edges),
+		AddedNodes:     len(r.AddedNodes),
+		RemovedNodes:   len(r.RemovedNodes),
+		AddedEdges:     len(r.AddedEdges),
+		RemovedEdges:   len(r.RemovedEdges),
+		NewlyReachable: len(r.NewlyReachable),
+		NewlyDead:      len(r.NewlyDead),
+	}
+	return r
+}
+
+func sortNodes(nodes []*NodeChange) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+}
+
+func sortEdges(edges []*EdgeChange) {
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.Caller != b.Caller {
+			return a.Caller < b.Caller
+		}
+		if a.Callee != b.Callee {
+			return a.Callee < b.Callee
+		}
+		return a.Kind < b.Kind
+	})
+}
+
+/* ============================================================================
+ * Empty
+ * ----------------------------------------------------------------------------
+ * Reports whether the two sides have the same functions, edges and
+ * reachability.
+ * ============================================================================
+ */
+func (r *Result) Empty() bool {
+	s := r.Summary
+	return s.AddedNodes+s.RemovedNodes+s.AddedEdges+s.RemovedEdges+s.NewlyReachable+s.NewlyDead == 0
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+/* ============================================================================
+ * WriteFile
+ * ----------------------------------------------------------------------------
+ * Writes doc to path in the format given by the path's extension.
+ * ============================================================================
+ */
+func WriteFile(path string, doc *Document) error {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+
+	if err := Write(w, doc, format); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+/* ============================================================================
+ * Write
+ * ----------------------------------------------------------------------------
+ * Writes doc to w in the given format.
+ * ============================================================================
+ */
+func Write(w io.Writer, doc *Document, format Format) error {
+	switch format {
+	case FormatJSON:    return WriteJSON(w, doc)
+	case FormatGraphML: return WriteGraphML(w, doc)
+	case FormatGEXF:    return WriteGEXF(w, doc)
+	}
+	return fmt.Errorf("unknown export format %v", format)
+}
+
+/* ============================================================================
+ * WriteJSON
+ * ----------------------------------------------------------------------------
+ * Writes doc as indented JSON following graph.schema.json.
+ * ============================================================================
+ */
+func WriteJSON(w io.Writer, doc *Document) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+/* ============================================================================
+ * ReadFile
+ * ----------------------------------------------------------------------------
+ * Reads a Document back from a JSON export. GraphML and GEXF exports are
+ * not read, as they flatten flags and positions into strings.
+ * ============================================================================
+ */
+func ReadFile(path string) (*Document, error) {
+	if format, err := FormatFromPath(path); err != nil || format != FormatJSON {
+		return nil, fmt.Errorf("%s: only .json exports can be read back", path)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc Document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if doc.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("%s: schema version %d, want %d", path, doc.SchemaVersion, SchemaVersion)
+	}
+	return &doc, nil
+}
@@ -0,0 +1,337 @@
+package export
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	cs_callgraph "callstat/CS-Callgraph"
+)
+
+// Bumped whenever a field of Document, NodeRecord or EdgeRecord changes meaning
+const SchemaVersion = 1
+
+/* ============================================================================
+ * Document
+ * ----------------------------------------------------------------------------
+ * The format-independent form of a whole cs_callgraph.Graph. Every exporter
+ * writes a Document; the JSON exporter writes it as-is, so these structs
+ * are the documented JSON schema (see graph.schema.json).
+ *
+ *   SchemaVersion  SchemaVersion at the time of writing
+ *   ProjectRoot    module path of the analysed project
+ *   K              call-site context depth of the graph (0 = collapsed)
+ *   Nodes          every node except the synthetic root: functions by ID,
+ *                  then interface methods, then the panic sink
+ *   Edges          every edge between exported nodes, grouped by caller
+ * ============================================================================
+ */
+type Document struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	ProjectRoot   string        `json:"projectRoot"`
+	K             int           `json:"k"`
+	Nodes         []*NodeRecord `json:"nodes"`
+	Edges         []*EdgeRecord `json:"edges"`
+}
+
+/* ============================================================================
+ * NodeRecord
+ * ----------------------------------------------------------------------------
+ *   ID       stable within one export: "n<id>" function, "iface<id>"
+ *            interface method, "panic" for the panic sink
+ *   Name     fully qualified name, plus " @[file:line → …]" for contexts
+ *   Package  owning package path ("" for the panic sink)
+ *   Depth    package depth from the project (-1 = unknown)
+ *   Kind     function | method | closure | wrapper | interface | panic
+ *   Body     bodiless classification (asm, cgo, linkname, external);
+ *            omitted for ordinary Go bodies
+ *   Flags    any of: entry, reachable, project, stdlib, instance, generic
+ *   Base     context nodes only: ID of the node they refine in an
+ *            export of the collapsed graph
+ * ============================================================================
+ */
+type NodeRecord struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Package string   `json:"package"`
+	Depth   int      `json:"depth"`
+	Kind    string   `json:"kind"`
+	Body    string   `json:"body,omitempty"`
+	Flags   []string `json:"flags"`
+	Base    string   `json:"base,omitempty"`
+}
+
+/* ============================================================================
+ * EdgeRecord
+ * ----------------------------------------------------------------------------
+ *   ID          "e<index>"
+ *   Source      caller NodeRecord.ID
+ *   Target      callee NodeRecord.ID
+ *   Kind        cs_callgraph.EdgeKind name (call, go, defer, assign, …)
+ *   Provenance  analysis that produced it (syntactic, cha, rta, vta)
+ *   Sites       number of call sites
+ *   Positions   source position of each site that has one
+ * ============================================================================
+ */
+type EdgeRecord struct {
+	ID         string     `json:"id"`
+	Source     string     `json:"source"`
+	Target     string     `json:"target"`
+	Kind       string     `json:"kind"`
+	Provenance string     `json:"provenance"`
+	Sites      int        `json:"sites"`
+	Positions  []Position `json:"positions"`
+}
+
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+/* ============================================================================
+ * Format
+ * ----------------------------------------------------------------------------
+ * Output format of an export, chosen from the file extension.
+ *
+ *   FormatJSON     .json     the Document schema
+ *   FormatGraphML  .graphml  GraphML 1.0 (networkx, yEd, Gephi)
+ *   FormatGEXF     .gexf     GEXF 1.3 (Gephi)
+ * ============================================================================
+ */
+type Format int
+
+const (
+	FormatJSON Format = iota
+	FormatGraphML
+	FormatGEXF
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:    return "json"
+	case FormatGraphML: return "graphml"
+	case FormatGEXF:    return "gexf"
+	default:            return "unknown"
+	}
+}
+
+/* ============================================================================
+ * FormatFromPath
+ * ----------------------------------------------------------------------------
+ * Picks the Format for an output path by its extension.
+ * ============================================================================
+ */
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":    return FormatJSON, nil
+	case ".graphml": return FormatGraphML, nil
+	case ".gexf":    return FormatGEXF, nil
+	}
+	return FormatJSON, fmt.Errorf("unknown export format for %q (want .json, .graphml or .gexf)", path)
+}
+
+/* ============================================================================
+ * Build
+ * ----------------------------------------------------------------------------
+ * Converts g into a Document. Every function, interface method and context
+ * node is included regardless of depth, so the export is the whole graph;
+ * Depth lets consumers filter. The synthetic root is left out - its targets
+ * carry the "entry" flag instead.
+ * ============================================================================
+ */
+func Build(
+	g           *cs_callgraph.Graph,
+	depthMap    map[string]int,
+	projectRoot string,
+) *Document {
+	doc := &Document{
+		SchemaVersion: SchemaVersion,
+		ProjectRoot:   projectRoot,
+		K:             g.K,
+		Nodes:         []*NodeRecord{},
+		Edges:         []*EdgeRecord{},
+	}
+
+	entries := make(map[*cs_callgraph.Node]bool)
+	for _, n := range g.Entries() {
+		entries[n] = true
+	}
+	reachable := reachableFrom(g.Entries())
+
+	nodes := g.FunctionNodes()
+	nodes  = append(nodes, g.InterfaceNodes()...)
+
+	ids := make(map[*cs_callgraph.Node]string, len(nodes)+1)
+	for _, n := range nodes {
+		if n == g.Root || (n.Func == nil && n.IfaceMethod == nil) {
+			continue
+		}
+		rec := buildNode(n, depthMap, projectRoot, g.Std, entries[n], reachable[n])
+		ids[n] = rec.ID
+		doc.Nodes = append(doc.Nodes, rec)
+	}
+
+	for _, n := range nodes {
+		if _, ok := ids[n]; !ok {
+			continue
+		}
+		for _, e := range n.Out {
+			if e.Callee == g.PanicNode {
+				if _, ok := ids[e.Callee]; !ok {
+					ids[e.Callee] = "panic"
+					doc.Nodes = append(doc.Nodes, &NodeRecord{
+						ID: "panic", Name: "PANIC", Depth: -1, Kind: "panic", Flags: []string{},
+					})
+				}
+			}
+			target, ok := ids[e.Callee]
+			if !ok {
+				continue
+			}
+			doc.Edges = append(doc.Edges, buildEdge(len(doc.Edges), ids[n], target, e))
+		}
+	}
+	return doc
+}
+
+/* ============================================================================
+ * buildNode / buildEdge
+ * ----------------------------------------------------------------------------
+ * Fill one NodeRecord / EdgeRecord from the graph.
+ * ============================================================================
+ */
+func buildNode(
+	n           *cs_callgraph.Node,
+	depthMap    map[string]int,
+	projectRoot string,
+	std         cs_callgraph.STDLib,
+	entry       bool,
+	reachable   bool,
+) *NodeRecord {
+	rec := &NodeRecord{
+		ID:    nodeID(n),
+		Depth: -1,
+		Flags: []string{},
+	}
+	if n.Base != nil {
+		rec.Base = nodeID(n.Base)
+	}
+
+	if n.IfaceMethod != nil {
+		rec.Name = n.IfaceMethod.FullName() + n.ContextString()
+		rec.Kind = "interface"
+		if n.IfaceMethod.Pkg() != nil {
+			rec.Package = n.IfaceMethod.Pkg().Path()
+		}
+	} else {
+		rec.Name = n.Func.String() + n.ContextString()
+		rec.Kind = funcKind(n)
+		if pkg := cs_callgraph.EffectivePkg(n.Func); pkg != nil && pkg.Pkg != nil {
+			rec.Package = pkg.Pkg.Path()
+		}
+		if n.Body != cs_callgraph.BodySource {
+			rec.Body = n.Body.String()
+		}
+	}
+	if d, ok := depthMap[rec.Package]; ok {
+		rec.Depth = d
+	}
+
+	if entry {
+		rec.Flags = append(rec.Flags, "entry")
+	}
+	if reachable {
+		rec.Flags = append(rec.Flags, "reachable")
+	}
+	if projectRoot != "" && strings.HasPrefix(rec.Package, projectRoot) {
+		rec.Flags = append(rec.Flags, "project")
+	}
+	if std.Contains(rec.Package) {
+		rec.Flags = append(rec.Flags, "stdlib")
+	}
+	if n.Func != nil {
+		if origin := n.Func.Origin(); origin != nil && origin != n.Func {
+			rec.Flags = append(rec.Flags, "instance")
+		} else if n.Func.TypeParams().Len() > 0 {
+			rec.Flags = append(rec.Flags, "generic")
+		}
+	}
+	return rec
+}
+
+func buildEdge(index int, source, target string, e *cs_callgraph.Edge) *EdgeRecord {
+	rec := &EdgeRecord{
+		ID:         fmt.Sprintf("e%d", index),
+		Source:     source,
+		Target:     target,
+		Kind:       e.Kind.String(),
+		Provenance: e.Prov.String(),
+		Sites:      len(e.Sites),
+		Positions:  []Position{},
+	}
+	for _, p := range e.Positions() {
+		rec.Positions = append(rec.Positions, Position{File: p.Filename, Line: p.Line, Column: p.Column})
+	}
+	return rec
+}
+
+func nodeID(n *cs_callgraph.Node) string {
+	if n.IfaceMethod != nil {
+		return fmt.Sprintf("iface%d", -n.ID)
+	}
+	return fmt.Sprintf("n%d", n.ID)
+}
+
+/* ============================================================================
+ * funcKind
+ * ----------------------------------------------------------------------------
+ * Classifies a function node: closures have a parent, wrappers are
+ * synthesized by SSA (bound/thunk/promotion), methods have a receiver.
+ * Instances take the kind of their generic origin; package initializers,
+ * although synthetic, count as functions.
+ * ============================================================================
+ */
+func funcKind(n *cs_callgraph.Node) string {
+	fn := n.Func
+	if origin := fn.Origin(); origin != nil && origin != fn {
+		fn = origin
+	}
+	switch {
+	case fn.Parent() != nil:
+		return "closure"
+	case fn.Synthetic != "" && fn.Synthetic != "package initializer":
+		return "wrapper"
+	case fn.Signature.Recv() != nil:
+		return "method"
+	default:
+		return "function"
+	}
+}
+
+/* ============================================================================
+ * reachableFrom
+ * ----------------------------------------------------------------------------
+ * Returns every node reachable from the entries along out-edges.
+ * ============================================================================
+ */
+func reachableFrom(entries []*cs_callgraph.Node) map[*cs_callgraph.Node]bool {
+	seen  := make(map[*cs_callgraph.Node]bool)
+	stack := append([]*cs_callgraph.Node(nil), entries...)
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		for _, e := range n.Out {
+			stack = append(stack, e.Callee)
+		}
+	}
+	return seen
+}
@@ -0,0 +1,431 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cs_callgraph "callstat/CS-Callgraph"
+	rules "callstat/Rules"
+	stats "callstat/Statistics"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifRootID  = "SRCROOT"
+)
+
+/* ============================================================================
+ * SARIF rules
+ * ----------------------------------------------------------------------------
+ *   unreachable-function  in-depth function not reachable from any entry
+ *   reachable-sink        function marked with -sink reached from an entry
+ *   recursion-cycle       strongly connected component of the call graph
+ *   architecture-rule     call (chain) violating a -rules constraint
+ * ============================================================================
+ */
+type sarifRuleDef struct {
+	id    string
+	level string
+	short string
+	full  string
+}
+
+var sarifRuleDefs = []sarifRuleDef{
+	{
+		id:    "unreachable-function",
+		level: "warning",
+		short: "Function is not reachable from any entry point",
+		full:  "No call chain in the call graph leads from an entry point to this function. It is either dead code or only reached in ways the analysis cannot see (reflection, linkname, plugins).",
+	},
+	{
+		id:    "reachable-sink",
+		level: "warning",
+		short: "Sink function is reachable from an entry point",
+		full:  "A function matched by a -sink pattern can be called from an entry point. The message gives the shortest call chain as witness.",
+	},
+	{
+		id:    "recursion-cycle",
+		level: "note",
+		short: "Functions form a recursion cycle",
+		full:  "These functions call each other (or themselves) in a cycle, so the call depth is bounded only at run time.",
+	},
+	{
+		id:    "architecture-rule",
+		level: "error",
+		short: "Call violates an architecture rule",
+		full:  "A call, or a chain of calls, crosses package layers that a -rules constraint keeps apart. The message names the rule and gives the offending call chain.",
+	},
+}
+
+/* ============================================================================
+ * SARIFLog
+ * ----------------------------------------------------------------------------
+ * A SARIF 2.1.0 log with a single run, as read by code review tooling.
+ * Only the properties callstat fills are modelled.
+ * ============================================================================
+ */
+type SARIFLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLoc `json:"originalUriBaseIds,omitempty"`
+	Results            []*sarifResult              `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string       `json:"name"`
+	Rules []*sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	FullDescription      sarifMessage `json:"fullDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifResult struct {
+	RuleID           string           `json:"ruleId"`
+	RuleIndex        int              `json:"ruleIndex"`
+	Level            string           `json:"level"`
+	Message          sarifMessage     `json:"message"`
+	Locations        []*sarifLocation `json:"locations"`
+	RelatedLocations []*sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	ID               *int                    `json:"id,omitempty"`
+	PhysicalLocation *sarifPhysicalLocation  `json:"physicalLocation,omitempty"`
+	LogicalLocations []*sarifLogicalLocation `json:"logicalLocations,omitempty"`
+	Message          *sarifMessage           `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+	Region           *sarifRegion     `json:"region,omitempty"`
+}
+
+type sarifArtifactLoc struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+/* ============================================================================
+ * BuildSARIF
+ * ----------------------------------------------------------------------------
+ * Turns the per-function findings of report into SARIF results:
+ *
+ *   - every PackageStats.Unused entry with a source position ("not
+ *     reachable from <entry>", plus its size if -func-metrics ran);
+ *     synthetic functions without source are left out
+ *   - every reached sink, with the shortest witness chain as message and
+ *     the chain's functions as related locations
+ *   - every recursion cycle, located at its first member
+ *   - every -rules violation, located at the offending caller, with the
+ *     call chain as message and related locations
+ *
+ * g is the graph report was gathered from; it locates sinks and cycle
+ * members. Files under srcRoot are written relative to it (uriBaseId
+ * SRCROOT), all others as absolute file URIs.
+ * ============================================================================
+ */
+func BuildSARIF(g *cs_callgraph.Graph, report *stats.CallGraphReport, srcRoot string) *SARIFLog {
+	b := &sarifBuilder{root: srcRoot, locations: nodeLocations(g), results: []*sarifResult{}}
+	if abs, err := filepath.Abs(srcRoot); err == nil && srcRoot != "" {
+		b.root = abs
+	}
+
+	b.unreachable(report)
+	b.sinks(report.Sinks)
+	b.cycles(report.Cycles)
+	b.violations(report.Rules)
+
+	rules := make([]*sarifRule, 0, len(sarifRuleDefs))
+	for _, def := range sarifRuleDefs {
+		rule := &sarifRule{
+			ID:               def.id,
+			ShortDescription: sarifMessage{Text: def.short},
+			FullDescription:  sarifMessage{Text: def.full},
+		}
+		rule.DefaultConfiguration.Level = def.level
+		rules = append(rules, rule)
+	}
+
+	run := &sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "callstat", Rules: rules}},
+		Results: b.results,
+	}
+	if b.root != "" {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLoc{
+			sarifRootID: {URI: "file://" + filepath.ToSlash(b.root) + "/"},
+		}
+	}
+	return &SARIFLog{Schema: sarifSchema, Version: sarifVersion, Runs: []*sarifRun{run}}
+}
+
+/* ============================================================================
+ * WriteSARIFFile
+ * ----------------------------------------------------------------------------
+ * Writes log to path as indented JSON.
+ * ============================================================================
+ */
+func WriteSARIFFile(path string, log *SARIFLog) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(log); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+/* -------------------------------------------------------
+ * sarifBuilder
+ * Collects results; locations maps the context-free name
+ * (Func.String / IfaceMethod.FullName) to the declaration
+ * of every function and interface method, so context
+ * copies of an expanded graph share one entry.
+ * ------------------------------------------------------- */
+type sarifBuilder struct {
+	root      string
+	locations map[string]*stats.FunctionLocation
+	results   []*sarifResult
+}
+
+func nodeLocations(g *cs_callgraph.Graph) map[string]*stats.FunctionLocation {
+	locations := make(map[string]*stats.FunctionLocation)
+	var fset *token.FileSet
+	for _, n := range g.FunctionNodes() {
+		if n.Func != nil {
+			fset = n.Func.Prog.Fset
+			locations[n.Func.String()] = stats.NodeLocation(n, fset)
+		}
+	}
+	for _, n := range g.InterfaceNodes() {
+		locations[n.IfaceMethod.FullName()] = stats.NodeLocation(n, fset)
+	}
+	return locations
+}
+
+func (b *sarifBuilder) add(rule int, msg string, loc *stats.FunctionLocation, related []*stats.FunctionLocation) {
+	def := sarifRuleDefs[rule]
+	r := &sarifResult{
+		RuleID:    def.id,
+		RuleIndex: rule,
+		Level:     def.level,
+		Message:   sarifMessage{Text: msg},
+		Locations: []*sarifLocation{b.location(loc)},
+	}
+	for i, rel := range related {
+		id := i
+		l  := b.location(rel)
+		l.ID      = &id
+		l.Message = &sarifMessage{Text: rel.Function}
+		r.RelatedLocations = append(r.RelatedLocations, l)
+	}
+	b.results = append(b.results, r)
+}
+
+func (b *sarifBuilder) location(loc *stats.FunctionLocation) *sarifLocation {
+	l := &sarifLocation{
+		LogicalLocations: []*sarifLogicalLocation{{FullyQualifiedName: loc.Function, Kind: "function"}},
+	}
+	if loc.File == "" {
+		return l
+	}
+	artifact := sarifArtifactLoc{URI: "file://" + filepath.ToSlash(loc.File)}
+	if b.root != "" {
+		if rel, err := filepath.Rel(b.root, loc.File); err == nil && !strings.HasPrefix(rel, "..") {
+			artifact = sarifArtifactLoc{URI: filepath.ToSlash(rel), URIBaseID: sarifRootID}
+		}
+	}
+	l.PhysicalLocation = &sarifPhysicalLocation{
+		ArtifactLocation: artifact,
+		Region:           &sarifRegion{StartLine: loc.Line, StartColumn: loc.Column},
+	}
+	return l
+}
+
+// lookup returns the location of a function by name, or a bare name. A
+// context suffix (" @[...]", see Node.ContextString) is kept in the name
+// but ignored for the lookup.
+func (b *sarifBuilder) lookup(name string) *stats.FunctionLocation {
+	base := name
+	if i := strings.Index(name, " @["); i >= 0 {
+		base = name[:i]
+	}
+	if loc, ok := b.locations[base]; ok {
+		at := *loc
+		at.Function = name
+		return &at
+	}
+	return &stats.FunctionLocation{Function: name}
+}
+
+/* -------------------------------------------------------
+ * unreachable
+ * ------------------------------------------------------- */
+func (b *sarifBuilder) unreachable(report *stats.CallGraphReport) {
+	var from string
+	switch entries := report.EntryPoints; {
+	case len(entries) == 0:
+		from = "no entry points were found"
+	case len(entries) == 1:
+		from = "not reachable from " + entries[0]
+	case len(entries) <= 3:
+		from = fmt.Sprintf("not reachable from any of %s", strings.Join(entries, ", "))
+	default:
+		from = fmt.Sprintf("not reachable from any of the %d entry points (%s, …)",
+			len(entries), strings.Join(entries[:3], ", "))
+	}
+
+	metrics := make(map[string]*stats.FunctionMetrics, len(report.Functions))
+	for _, m := range report.Functions {
+		metrics[m.Function] = m
+	}
+
+	paths := make([]string, 0, len(report.Packages))
+	for path := range report.Packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		unused := append([]*stats.FunctionLocation(nil), report.Packages[path].Unused...)
+		sort.Slice(unused, func(i, j int) bool {
+			if unused[i].File != unused[j].File {
+				return unused[i].File < unused[j].File
+			}
+			return unused[i].Line < unused[j].Line
+		})
+		for _, loc := range unused {
+			if loc.File == "" {
+				continue
+			}
+			msg := fmt.Sprintf("%s is %s", loc.Function, from)
+			if m, ok := metrics[loc.Function]; ok && m.Lines > 0 {
+				msg += fmt.Sprintf(" (%d line(s), cyclomatic complexity %d)", m.Lines, m.Cyclomatic)
+			}
+			b.add(0, msg, loc, nil)
+		}
+	}
+}
+
+/* -------------------------------------------------------
+ * sinks
+ * ------------------------------------------------------- */
+func (b *sarifBuilder) sinks(report *stats.SinkReport) {
+	if report == nil {
+		return
+	}
+	for _, sink := range report.Sinks {
+		if len(sink.Entries) == 0 {
+			continue
+		}
+		witness := sink.Entries[0]
+		names   := make([]string, 0, len(witness.Chain))
+		related := make([]*stats.FunctionLocation, 0, len(witness.Chain))
+		for _, step := range witness.Chain {
+			names   = append(names, step.Function)
+			related = append(related, b.lookup(step.Function))
+		}
+		msg := fmt.Sprintf("Sink %s is reachable from %s via %s", sink.Sink, witness.Entry, strings.Join(names, " → "))
+		if more := len(sink.Entries) - 1; more > 0 {
+			msg += fmt.Sprintf(" (and from %d more entry point(s))", more)
+		}
+		b.add(1, msg, b.lookup(sink.Sink), related)
+	}
+}
+
+/* -------------------------------------------------------
+ * cycles
+ * ------------------------------------------------------- */
+func (b *sarifBuilder) cycles(report *stats.CycleReport) {
+	if report == nil {
+		return
+	}
+	for _, c := range report.Cycles {
+		members := append([]string(nil), c.Functions...)
+		sort.Strings(members)
+
+		var msg string
+		if c.Size == 1 {
+			msg = fmt.Sprintf("%s calls itself recursively", members[0])
+		} else {
+			msg = fmt.Sprintf("Recursion cycle %d of %d functions: %s", c.ID, c.Size, strings.Join(members, ", "))
+		}
+		related := make([]*stats.FunctionLocation, 0, len(members))
+		for _, name := range members {
+			related = append(related, b.lookup(name))
+		}
+		if c.Size == 1 {
+			related = nil
+		}
+		b.add(2, msg, b.lookup(members[0]), related)
+	}
+}
+
+/* -------------------------------------------------------
+ * violations
+ * ------------------------------------------------------- */
+func (b *sarifBuilder) violations(report *rules.Report) {
+	if report == nil {
+		return
+	}
+	for _, rr := range report.Rules {
+		for _, v := range rr.Violations {
+			names   := make([]string, 0, len(v.Chain))
+			related := make([]*stats.FunctionLocation, 0, len(v.Chain))
+			for _, step := range v.Chain {
+				names   = append(names, step.Function)
+				related = append(related, b.lookup(step.Function))
+			}
+			msg := fmt.Sprintf("Rule %q: %s calls %s", rr.Name, v.Caller, v.Callee)
+			if len(v.Chain) > 2 {
+				msg = fmt.Sprintf("Rule %q: %s reaches %s via %s", rr.Name, v.Caller, v.Callee, strings.Join(names, " → "))
+			}
+			b.add(3, msg, b.lookup(v.Caller), related)
+		}
+	}
+}
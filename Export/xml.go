@@ -0,0 +1,250 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/* ============================================================================
+ * XML attribute tables
+ * ----------------------------------------------------------------------------
+ * GraphML and GEXF both declare typed attributes up front and then attach
+ * values per node / edge. These tables map the Document fields onto such
+ * attributes once for both formats. Lists (flags, positions) are flattened
+ * into one string, since neither format has a list type networkx and Gephi
+ * both read.
+ *
+ *   flags      comma-separated, e.g. "entry,reachable,project"
+ *   positions  semicolon-separated "file:line:column"
+ * ============================================================================
+ */
+type xmlAttr struct {
+	name string
+	typ  string // "string" or "int"
+	node func(*NodeRecord) string
+	edge func(*EdgeRecord) string
+}
+
+var nodeAttrs = []xmlAttr{
+	{name: "name",    typ: "string", node: func(n *NodeRecord) string { return n.Name }},
+	{name: "package", typ: "string", node: func(n *NodeRecord) string { return n.Package }},
+	{name: "depth",   typ: "int",    node: func(n *NodeRecord) string { return strconv.Itoa(n.Depth) }},
+	{name: "kind",    typ: "string", node: func(n *NodeRecord) string { return n.Kind }},
+	{name: "body",    typ: "string", node: func(n *NodeRecord) string { return n.Body }},
+	{name: "flags",   typ: "string", node: func(n *NodeRecord) string { return strings.Join(n.Flags, ",") }},
+	{name: "base",    typ: "string", node: func(n *NodeRecord) string { return n.Base }},
+}
+
+var edgeAttrs = []xmlAttr{
+	{name: "kind",       typ: "string", edge: func(e *EdgeRecord) string { return e.Kind }},
+	{name: "provenance", typ: "string", edge: func(e *EdgeRecord) string { return e.Provenance }},
+	{name: "sites",      typ: "int",    edge: func(e *EdgeRecord) string { return strconv.Itoa(e.Sites) }},
+	{name: "positions",  typ: "string", edge: func(e *EdgeRecord) string { return joinPositions(e.Positions) }},
+}
+
+func joinPositions(ps []Position) string {
+	parts := make([]string, len(ps))
+	for i, p := range ps {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, ";")
+}
+
+/* ============================================================================
+ * GraphML
+ * ============================================================================
+ */
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+/* ============================================================================
+ * WriteGraphML
+ * ----------------------------------------------------------------------------
+ * Writes doc as a directed GraphML 1.0 graph. Keys are "n_<attr>" for node
+ * and "e_<attr>" for edge attributes; empty values are omitted.
+ * ============================================================================
+ */
+func WriteGraphML(w io.Writer, doc *Document) error {
+	out := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Graph: graphMLGraph{ID: "callgraph", EdgeDefault: "directed"},
+	}
+	for _, a := range nodeAttrs {
+		out.Keys = append(out.Keys, graphMLKey{ID: "n_" + a.name, For: "node", AttrName: a.name, AttrType: a.typ})
+	}
+	for _, a := range edgeAttrs {
+		out.Keys = append(out.Keys, graphMLKey{ID: "e_" + a.name, For: "edge", AttrName: a.name, AttrType: a.typ})
+	}
+
+	for _, n := range doc.Nodes {
+		gn := graphMLNode{ID: n.ID}
+		for _, a := range nodeAttrs {
+			if v := a.node(n); v != "" {
+				gn.Data = append(gn.Data, graphMLData{Key: "n_" + a.name, Value: v})
+			}
+		}
+		out.Graph.Nodes = append(out.Graph.Nodes, gn)
+	}
+	for _, e := range doc.Edges {
+		ge := graphMLEdge{ID: e.ID, Source: e.Source, Target: e.Target}
+		for _, a := range edgeAttrs {
+			if v := a.edge(e); v != "" {
+				ge.Data = append(ge.Data, graphMLData{Key: "e_" + a.name, Value: v})
+			}
+		}
+		out.Graph.Edges = append(out.Graph.Edges, ge)
+	}
+	return writeXML(w, out)
+}
+
+/* ============================================================================
+ * GEXF
+ * ============================================================================
+ */
+type gexf struct {
+	XMLName xml.Name  `xml:"gexf"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Mode            string           `xml:"mode,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class string          `xml:"class,attr"`
+	Attrs []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	ID     string          `xml:"id,attr"`
+	Label  string          `xml:"label,attr"`
+	Values []gexfAttrValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID     string          `xml:"id,attr"`
+	Source string          `xml:"source,attr"`
+	Target string          `xml:"target,attr"`
+	Label  string          `xml:"label,attr"`
+	Weight int             `xml:"weight,attr"`
+	Values []gexfAttrValue `xml:"attvalues>attvalue"`
+}
+
+type gexfAttrValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+/* ============================================================================
+ * WriteGEXF
+ * ----------------------------------------------------------------------------
+ * Writes doc as a static, directed GEXF 1.3 graph. Node labels are the
+ * function names, edge labels the edge kinds; the edge weight is the
+ * number of call sites (at least 1, as Gephi expects positive weights).
+ * ============================================================================
+ */
+func WriteGEXF(w io.Writer, doc *Document) error {
+	out := gexf{
+		XMLNS:   "http://gexf.net/1.3",
+		Version: "1.3",
+		Graph:   gexfGraph{DefaultEdgeType: "directed", Mode: "static"},
+	}
+	nodeDecl := gexfAttributes{Class: "node"}
+	for _, a := range nodeAttrs {
+		nodeDecl.Attrs = append(nodeDecl.Attrs, gexfAttribute{ID: a.name, Title: a.name, Type: gexfType(a.typ)})
+	}
+	edgeDecl := gexfAttributes{Class: "edge"}
+	for _, a := range edgeAttrs {
+		edgeDecl.Attrs = append(edgeDecl.Attrs, gexfAttribute{ID: a.name, Title: a.name, Type: gexfType(a.typ)})
+	}
+	out.Graph.Attributes = []gexfAttributes{nodeDecl, edgeDecl}
+
+	for _, n := range doc.Nodes {
+		gn := gexfNode{ID: n.ID, Label: n.Name}
+		for _, a := range nodeAttrs {
+			if v := a.node(n); v != "" {
+				gn.Values = append(gn.Values, gexfAttrValue{For: a.name, Value: v})
+			}
+		}
+		out.Graph.Nodes = append(out.Graph.Nodes, gn)
+	}
+	for _, e := range doc.Edges {
+		ge := gexfEdge{ID: e.ID, Source: e.Source, Target: e.Target, Label: e.Kind, Weight: max(1, e.Sites)}
+		for _, a := range edgeAttrs {
+			if v := a.edge(e); v != "" {
+				ge.Values = append(ge.Values, gexfAttrValue{For: a.name, Value: v})
+			}
+		}
+		out.Graph.Edges = append(out.Graph.Edges, ge)
+	}
+	return writeXML(w, out)
+}
+
+func gexfType(typ string) string {
+	if typ == "int" {
+		return "integer"
+	}
+	return typ
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
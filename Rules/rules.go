@@ -0,0 +1,386 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	cs_callgraph "callstat/CS-Callgraph"
+)
+
+/* ============================================================================
+ * Kind
+ * ----------------------------------------------------------------------------
+ * How a Rule constrains calls from its From packages into its To packages.
+ *
+ *   KindDeny   functions in From must not call into To
+ *   KindAllow  only functions in From (and in To itself) may call into To
+ * ============================================================================
+ */
+type Kind int
+
+const (
+	KindDeny Kind = iota
+	KindAllow
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindDeny:  return "deny"
+	case KindAllow: return "allow"
+	default:        return "unknown"
+	}
+}
+
+/* ============================================================================
+ * ParseKind
+ * ----------------------------------------------------------------------------
+ * Converts a rules file value ("deny", "allow") into a Kind.
+ * ============================================================================
+ */
+func ParseKind(s string) (Kind, error) {
+	switch s {
+	case "deny":  return KindDeny, nil
+	case "allow": return KindAllow, nil
+	}
+	return KindDeny, fmt.Errorf("unknown rule kind %q (want deny or allow)", s)
+}
+
+/* ============================================================================
+ * Rule
+ * ----------------------------------------------------------------------------
+ * One constraint of a rules file. From and To are package patterns in the
+ * -skip-cg syntax (exact path, or trailing / for a prefix match).
+ *
+ *   Name        shown with every violation ("rule <n>" if empty)
+ *   Kind        "deny" or "allow"
+ *   From        deny: the constrained callers (empty = every package);
+ *               allow: the only callers permitted
+ *   To          the protected packages
+ *   Transitive  deny rules: check call chains of any length instead of
+ *               direct calls. Allow rules always check direct calls -
+ *               every chain into To that avoids From ends in one
+ * ============================================================================
+ */
+type Rule struct {
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"`
+	From       []string `json:"from"`
+	To         []string `json:"to"`
+	Transitive bool     `json:"transitive"`
+
+	kind Kind
+}
+
+/* ============================================================================
+ * RuleSet
+ * ----------------------------------------------------------------------------
+ * The contents of a rules file:
+ *
+ *   {
+ *     "rules": [
+ *       { "name": "storage stays below http", "kind": "deny",
+ *         "from": ["ex/internal/storage/"], "to": ["ex/internal/http/"],
+ *         "transitive": true },
+ *       { "name": "only db talks SQL", "kind": "allow",
+ *         "from": ["ex/db"], "to": ["database/sql"] }
+ *     ]
+ *   }
+ * ============================================================================
+ */
+type RuleSet struct {
+	Rules []*Rule `json:"rules"`
+}
+
+/* ============================================================================
+ * Load
+ * ----------------------------------------------------------------------------
+ * Reads and validates a rules file.
+ * ============================================================================
+ */
+func Load(path string) (*RuleSet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set RuleSet
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	for i, r := range set.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if r.Kind == "" {
+			r.Kind = KindDeny.String()
+		}
+		if r.kind, err = ParseKind(r.Kind); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, r.Name, err)
+		}
+		if len(r.To) == 0 {
+			return nil, fmt.Errorf("%s: %s: \"to\" is empty", path, r.Name)
+		}
+		if r.kind == KindAllow && len(r.From) == 0 {
+			return nil, fmt.Errorf("%s: %s: allow rule without \"from\"", path, r.Name)
+		}
+	}
+	return &set, nil
+}
+
+/* ============================================================================
+ * Report / RuleResult / Violation
+ * ----------------------------------------------------------------------------
+ * Outcome of Check: every rule with its violations (empty if it holds).
+ * A Violation's Chain starts with the offending caller and ends with the
+ * protected function; every step after the first names the edge kind and
+ * first call site that leads into it.
+ * ============================================================================
+ */
+type Report struct {
+	Violations int           `json:"violations"`
+	Rules      []*RuleResult `json:"rules"`
+}
+
+type RuleResult struct {
+	*Rule
+	Violations []*Violation `json:"violations"`
+}
+
+type Violation struct {
+	Rule   string  `json:"rule"`
+	Caller string  `json:"caller"`
+	Callee string  `json:"callee"`
+	Chain  []*Step `json:"chain"`
+}
+
+type Step struct {
+	Function string `json:"function"`
+	Kind     string `json:"kind,omitempty"`
+	Site     string `json:"site,omitempty"`
+}
+
+/* ============================================================================
+ * Check
+ * ----------------------------------------------------------------------------
+ * Checks every rule of set against g (best the collapsed graph - context
+ * nodes would repeat each violation).
+ *
+ * Direct rules look at edges between two function nodes: calls, go, defer
+ * and function values. A call through an interface is not a direct call
+ * into the implementing package, so interface nodes are skipped.
+ *
+ * Transitive rules follow every call edge, dispatch included. Each
+ * constrained function that reaches a protected one yields a violation
+ * with the shortest chain; the chain stops at the first protected
+ * function.
+ * ============================================================================
+ */
+func Check(g *cs_callgraph.Graph, set *RuleSet) *Report {
+	report := &Report{Rules: []*RuleResult{}}
+	if set == nil {
+		return report
+	}
+
+	nodes := append(g.FunctionNodes(), g.InterfaceNodes()...)
+	for _, r := range set.Rules {
+		m := newMatcher(r)
+
+		var violations []*Violation
+		if r.Transitive && r.kind == KindDeny {
+			violations = checkTransitive(r, m, nodes)
+		} else {
+			violations = checkDirect(r, m, nodes)
+		}
+		sort.Slice(violations, func(i, j int) bool {
+			if violations[i].Caller != violations[j].Caller {
+				return violations[i].Caller < violations[j].Caller
+			}
+			return violations[i].Callee < violations[j].Callee
+		})
+
+		report.Violations += len(violations)
+		report.Rules = append(report.Rules, &RuleResult{Rule: r, Violations: violations})
+	}
+	return report
+}
+
+/* -------------------------------------------------------
+ * matcher
+ * Classifies nodes by package for one rule, caching the
+ * pattern match per package path.
+ * ------------------------------------------------------- */
+type matcher struct {
+	rule *Rule
+	from map[string]bool
+	to   map[string]bool
+}
+
+func newMatcher(r *Rule) *matcher {
+	return &matcher{rule: r, from: make(map[string]bool), to: make(map[string]bool)}
+}
+
+func (m *matcher) match(cache map[string]bool, patterns []string, pkg string) bool {
+	hit, ok := cache[pkg]
+	if !ok {
+		hit = cs_callgraph.MatchesPattern(pkg, patterns)
+		cache[pkg] = hit
+	}
+	return hit
+}
+
+// protected reports whether n belongs to one of the rule's To packages
+func (m *matcher) protected(n *cs_callgraph.Node) bool {
+	pkg := cs_callgraph.NodePackage(n)
+	return pkg != "" && m.match(m.to, m.rule.To, pkg)
+}
+
+// permitted reports whether n belongs to one of an allow rule's From packages
+func (m *matcher) permitted(n *cs_callgraph.Node) bool {
+	return m.rule.kind == KindAllow && m.match(m.from, m.rule.From, cs_callgraph.NodePackage(n))
+}
+
+// constrained reports whether calls from n into To are violations
+func (m *matcher) constrained(n *cs_callgraph.Node) bool {
+	pkg := cs_callgraph.NodePackage(n)
+	if pkg == "" || m.protected(n) {
+		return false
+	}
+	if m.rule.kind == KindAllow {
+		return !m.permitted(n)
+	}
+	return len(m.rule.From) == 0 || m.match(m.from, m.rule.From, pkg)
+}
+
+/* -------------------------------------------------------
+ * checkDirect
+ * ------------------------------------------------------- */
+func checkDirect(r *Rule, m *matcher, nodes []*cs_callgraph.Node) []*Violation {
+	violations := []*Violation{}
+	seen       := make(map[[2]*cs_callgraph.Node]bool)
+
+	for _, n := range nodes {
+		if n.Func == nil || !m.constrained(n) {
+			continue
+		}
+		for _, e := range n.Out {
+			if !isCallEdge(e) || e.Callee.Func == nil || !m.protected(e.Callee) {
+				continue
+			}
+			if key := [2]*cs_callgraph.Node{n, e.Callee}; !seen[key] {
+				seen[key] = true
+				violations = append(violations, violation(r, []*cs_callgraph.Edge{e}))
+			}
+		}
+	}
+	return violations
+}
+
+/* -------------------------------------------------------
+ * checkTransitive
+ * Deny rules only. Walks back from the protected functions
+ * to find every node that can reach one, then searches
+ * forward from each constrained node among them for its
+ * nearest protected function.
+ * ------------------------------------------------------- */
+func checkTransitive(r *Rule, m *matcher, nodes []*cs_callgraph.Node) []*Violation {
+	reaches := make(map[*cs_callgraph.Node]bool)
+	queue   := []*cs_callgraph.Node{}
+	for _, n := range nodes {
+		if m.protected(n) {
+			reaches[n] = true
+			queue      = append(queue, n)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, e := range n.In {
+			if isCallEdge(e) && !reaches[e.Caller] {
+				reaches[e.Caller] = true
+				queue = append(queue, e.Caller)
+			}
+		}
+	}
+
+	violations := []*Violation{}
+	for _, src := range nodes {
+		if !reaches[src] || !m.constrained(src) {
+			continue
+		}
+		if edges := nearestProtected(src, m, reaches); edges != nil {
+			violations = append(violations, violation(r, edges))
+		}
+	}
+	return violations
+}
+
+// nearestProtected returns the shortest chain from src into To, or nil
+func nearestProtected(
+	src     *cs_callgraph.Node,
+	m       *matcher,
+	reaches map[*cs_callgraph.Node]bool,
+) []*cs_callgraph.Edge {
+	via   := map[*cs_callgraph.Node]*cs_callgraph.Edge{src: nil}
+	queue := []*cs_callgraph.Node{src}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, e := range n.Out {
+			c := e.Callee
+			if _, seen := via[c]; seen || !isCallEdge(e) || !reaches[c] {
+				continue
+			}
+			via[c] = e
+			if m.protected(c) {
+				return chainTo(c, via)
+			}
+			queue = append(queue, c)
+		}
+	}
+	return nil
+}
+
+// chainTo walks the BFS tree back from n to the source it was reached from
+func chainTo(n *cs_callgraph.Node, via map[*cs_callgraph.Node]*cs_callgraph.Edge) []*cs_callgraph.Edge {
+	var edges []*cs_callgraph.Edge
+	for e := via[n]; e != nil; e = via[e.Caller] {
+		edges = append(edges, e)
+	}
+	for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+		edges[i], edges[j] = edges[j], edges[i]
+	}
+	return edges
+}
+
+func violation(r *Rule, edges []*cs_callgraph.Edge) *Violation {
+	first, last := edges[0].Caller, edges[len(edges)-1].Callee
+	v := &Violation{
+		Rule:   r.Name,
+		Caller: first.QualifiedName(),
+		Callee: last.QualifiedName(),
+		Chain:  []*Step{{Function: first.QualifiedName()}},
+	}
+	for _, e := range edges {
+		step := &Step{Function: e.Callee.QualifiedName(), Kind: e.Kind.String()}
+		if pos := e.Positions(); len(pos) > 0 {
+			step.Site = fmt.Sprintf("%s:%d", filepath.Base(pos[0].Filename), pos[0].Line)
+		}
+		v.Chain = append(v.Chain, step)
+	}
+	return v
+}
+
+// isCallEdge mirrors the query edges (no root, panic or instance edges)
+// and drops calls into package initializers, which follow from imports
+// rather than from code
+func isCallEdge(e *cs_callgraph.Edge) bool {
+	switch e.Kind {
+	case cs_callgraph.EntryEdge, cs_callgraph.PanicEdge, cs_callgraph.InstanceEdge:
+		return false
+	}
+	fn := e.Callee.Func
+	return fn == nil || fn.Synthetic != "package initializer"
+}
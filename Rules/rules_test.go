@@ -0,0 +1,190 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"callstat/CS-Callgraph/cgtest"
+)
+
+// testGraph is the call graph every rule is checked against
+var testGraph = []string{
+	"ex/cmd.Main -> ex/ui.Render",
+	"ex/ui.Render -> ex/svc.Get",
+	"ex/ui.Render -> ex/db.Query",
+	"ex/ui.Render -go-> ex/db.Query",
+	"ex/svc.Get -> ex/db.Query",
+	"ex/svc.Get -> ex/dbx.Open",
+	"ex/db.Query -> ex/db.conn",
+	"ex/internal/cache/lru.Put -> ex/db.Query",
+	"ex/cmd.Main -instance-> ex/db.conn",
+}
+
+func loadRules(t *testing.T, data string) (*RuleSet, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		want []string // caller -> callee of each violation
+	}{
+		{
+			name: "deny exact package",
+			rule: `{"kind": "deny", "from": ["ex/ui"], "to": ["ex/db"]}`,
+			// the call and the go edge between the same pair count once
+			want: []string{"ex/ui.Render -> ex/db.Query"},
+		},
+		{
+			name: "deny from every package",
+			rule: `{"to": ["ex/db"]}`,
+			// ex/db.conn is called from inside ex/db, ex/dbx is another package
+			want: []string{
+				"ex/internal/cache/lru.Put -> ex/db.Query",
+				"ex/svc.Get -> ex/db.Query",
+				"ex/ui.Render -> ex/db.Query",
+			},
+		},
+		{
+			name: "deny prefix from",
+			rule: `{"from": ["ex/internal/"], "to": ["ex/db"]}`,
+			want: []string{"ex/internal/cache/lru.Put -> ex/db.Query"},
+		},
+		{
+			name: "prefix to does not match a sibling",
+			rule: `{"from": ["ex/svc"], "to": ["ex/db/"]}`,
+			want: []string{"ex/svc.Get -> ex/db.Query"},
+		},
+		{
+			name: "exact patterns for both",
+			rule: `{"from": ["ex/svc"], "to": ["ex/db", "ex/dbx"]}`,
+			want: []string{"ex/svc.Get -> ex/db.Query", "ex/svc.Get -> ex/dbx.Open"},
+		},
+		{
+			name: "allow",
+			rule: `{"kind": "allow", "from": ["ex/svc"], "to": ["ex/db"]}`,
+			want: []string{
+				"ex/internal/cache/lru.Put -> ex/db.Query",
+				"ex/ui.Render -> ex/db.Query",
+			},
+		},
+		{
+			name: "allow with prefix",
+			rule: `{"kind": "allow", "from": ["ex/svc", "ex/ui"], "to": ["ex/db/"]}`,
+			want: []string{"ex/internal/cache/lru.Put -> ex/db.Query"},
+		},
+		{
+			name: "direct deny misses indirect calls",
+			rule: `{"from": ["ex/cmd"], "to": ["ex/db"]}`,
+			// the instance edge Main -> conn is not a call
+			want: []string{},
+		},
+		{
+			name: "transitive deny",
+			rule: `{"from": ["ex/cmd"], "to": ["ex/db"], "transitive": true}`,
+			want: []string{"ex/cmd.Main -> ex/db.Query"},
+		},
+		{
+			name: "nothing to report",
+			rule: `{"from": ["ex/db"], "to": ["ex/ui"], "transitive": true}`,
+			want: []string{},
+		},
+	}
+
+	g, _ := cgtest.Build(t, testGraph...)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := loadRules(t, `{"rules": [`+tt.rule+`]}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report := Check(g, set)
+
+			got := []string{}
+			for _, v := range report.Rules[0].Violations {
+				got = append(got, v.Caller+" -> "+v.Callee)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations = %q, want %q", got, tt.want)
+			}
+			if report.Violations != len(tt.want) {
+				t.Errorf("report.Violations = %d, want %d", report.Violations, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestCheckReport(t *testing.T) {
+	set, err := loadRules(t, `{"rules": [
+		{"name": "ui stays off db", "from": ["ex/ui"], "to": ["ex/db"]},
+		{"from": ["ex/cmd"], "to": ["ex/db"], "transitive": true},
+		{"kind": "allow", "from": ["ex/ui"], "to": ["ex/svc"]}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, _ := cgtest.Build(t, testGraph...)
+	report := Check(g, set)
+
+	if report.Violations != 2 {
+		t.Errorf("report.Violations = %d, want 2", report.Violations)
+	}
+	var names []string
+	for _, r := range report.Rules {
+		names = append(names, r.Name)
+	}
+	if want := []string{"ui stays off db", "rule 2", "rule 3"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("rule names = %q, want %q", names, want)
+	}
+
+	direct := report.Rules[0].Violations[0]
+	want   := &Violation{
+		Rule:   "ui stays off db",
+		Caller: "ex/ui.Render",
+		Callee: "ex/db.Query",
+		Chain:  []*Step{{Function: "ex/ui.Render"}, {Function: "ex/db.Query", Kind: "call"}},
+	}
+	if !reflect.DeepEqual(direct, want) {
+		t.Errorf("direct violation = %+v, want %+v", direct, want)
+	}
+
+	// The shortest chain, Main -> Render -> Query, not the one through Get
+	chain := []*Step{
+		{Function: "ex/cmd.Main"},
+		{Function: "ex/ui.Render", Kind: "call"},
+		{Function: "ex/db.Query", Kind: "call"},
+	}
+	if got := report.Rules[1].Violations[0]; got.Rule != "rule 2" || !reflect.DeepEqual(got.Chain, chain) {
+		t.Errorf("transitive violation = %s %+v, want rule 2 %+v", got.Rule, got.Chain, chain)
+	}
+
+	if len(report.Rules[2].Violations) != 0 {
+		t.Errorf("allow rule: unexpected violations %+v", report.Rules[2].Violations)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string // after "<path>: "
+	}{
+		{`{"name": "r", "kind": "forbid", "to": ["ex/db"]}`, `r: unknown rule kind "forbid" (want deny or allow)`},
+		{`{"from": ["ex/ui"]}`, `rule 1: "to" is empty`},
+		{`{"kind": "allow", "to": ["ex/db"]}`, `rule 1: allow rule without "from"`},
+	}
+	for _, tt := range tests {
+		_, err := loadRules(t, `{"rules": [`+tt.rule+`]}`)
+		if err == nil || !strings.HasSuffix(err.Error(), ": "+tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.rule, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package stats
+
+import (
+	cs_callgraph "callstat/CS-Callgraph"
+	"sort"
+)
+
+/* ============================================================================
+ * FunctionCentrality
+ * ----------------------------------------------------------------------------
+ * One row of the per-function centrality table; see cs_callgraph.Centrality
+ * for the meaning of each metric.
+ * ============================================================================
+ */
+type FunctionCentrality struct {
+	Function       string  `json:"function"`
+	Package        string  `json:"package"`
+	FanIn          int     `json:"fanIn"`
+	FanOut         int     `json:"fanOut"`
+	CallerPackages int     `json:"callerPackages"`
+	Betweenness    float64 `json:"betweenness"`
+	PageRank       float64 `json:"pageRank"`
+}
+
+/* ============================================================================
+ * CentralityTable
+ * ----------------------------------------------------------------------------
+ * Flattens the metrics computed by cs_callgraph.ComputeCentrality into
+ * rows, strongest choke points first: by betweenness, then fan-in, then
+ * name. Returns nil for nil metrics.
+ * ============================================================================
+ */
+func CentralityTable(metrics map[*cs_callgraph.Node]*cs_callgraph.Centrality) []*FunctionCentrality {
+	if metrics == nil {
+		return nil
+	}
+	rows := make([]*FunctionCentrality, 0, len(metrics))
+	for n, c := range metrics {
+		pkgPath, _ := nodePkgPath(n)
+		rows = append(rows, &FunctionCentrality{
+			Function:       n.QualifiedName(),
+			Package:        pkgPath,
+			FanIn:          c.FanIn,
+			FanOut:         c.FanOut,
+			CallerPackages: c.CallerPackages,
+			Betweenness:    c.Betweenness,
+			PageRank:       c.PageRank,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Betweenness != b.Betweenness {
+			return a.Betweenness > b.Betweenness
+		}
+		if a.FanIn != b.FanIn {
+			return a.FanIn > b.FanIn
+		}
+		return a.Function < b.Function
+	})
+	return rows
+}
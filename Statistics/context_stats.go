@@ -0,0 +1,114 @@
+package stats
+
+import (
+	cs_callgraph "callstat/CS-Callgraph"
+	"sort"
+)
+
+/* ============================================================================
+ * ContextReport
+ * ----------------------------------------------------------------------------
+ * Measures how much k-CFA context expansion grew the call graph.
+ *
+ *   K                 call-site context depth used for the expansion
+ *   View              which graph the rest of the report describes
+ *                     ("collapsed" or "expanded")
+ *   CollapsedNodes    function + interface nodes before expansion
+ *   ExpandedNodes     (function, context) nodes after expansion
+ *   NodeGrowth        ExpandedNodes / CollapsedNodes
+ *   CollapsedEdges    edges before expansion
+ *   ExpandedEdges     edges after expansion
+ *   EdgeGrowth        ExpandedEdges / CollapsedEdges
+ *   MostContexts      functions with the most distinct contexts
+ * ============================================================================
+ */
+type ContextReport struct {
+	K              int            `json:"k"`
+	View           string         `json:"view"`
+	CollapsedNodes int            `json:"collapsedNodes"`
+	ExpandedNodes  int            `json:"expandedNodes"`
+	NodeGrowth     float64        `json:"nodeGrowth"`
+	CollapsedEdges int            `json:"collapsedEdges"`
+	ExpandedEdges  int            `json:"expandedEdges"`
+	EdgeGrowth     float64        `json:"edgeGrowth"`
+	MostContexts   []ContextCount `json:"mostContexts"`
+}
+
+type ContextCount struct {
+	Function string `json:"function"`
+	Contexts int    `json:"contexts"`
+}
+
+// Number of entries kept in ContextReport.MostContexts
+const mostContextsLimit = 20
+
+/* ============================================================================
+ * CompareContexts
+ * ----------------------------------------------------------------------------
+ * Builds a ContextReport from a collapsed graph and its ExpandContexts
+ * result. expandedView records which of the two the surrounding report was
+ * gathered over.
+ * ============================================================================
+ */
+func CompareContexts(
+	collapsed    *cs_callgraph.Graph,
+	expanded     *cs_callgraph.Graph,
+	expandedView bool,
+) *ContextReport {
+	r := &ContextReport{
+		K:    expanded.K,
+		View: "collapsed",
+	}
+	if expandedView {
+		r.View = "expanded"
+	}
+
+	for _, n := range append(collapsed.FunctionNodes(), collapsed.InterfaceNodes()...) {
+		r.CollapsedNodes++
+		r.CollapsedEdges += len(n.Out)
+	}
+
+	perFunc := make(map[string]int)
+	for _, n := range expanded.Contexts {
+		r.ExpandedNodes++
+		r.ExpandedEdges += len(n.Out)
+		perFunc[nodeName(n)]++
+	}
+
+	if r.CollapsedNodes > 0 {
+		r.NodeGrowth = float64(r.ExpandedNodes) / float64(r.CollapsedNodes)
+	}
+	if r.CollapsedEdges > 0 {
+		r.EdgeGrowth = float64(r.ExpandedEdges) / float64(r.CollapsedEdges)
+	}
+
+	for name, count := range perFunc {
+		r.MostContexts = append(r.MostContexts, ContextCount{name, count})
+	}
+	sort.Slice(r.MostContexts, func(i, j int) bool {
+		a, b := r.MostContexts[i], r.MostContexts[j]
+		if a.Contexts != b.Contexts {
+			return a.Contexts > b.Contexts
+		}
+		return a.Function < b.Function
+	})
+	if len(r.MostContexts) > mostContextsLimit {
+		r.MostContexts = r.MostContexts[:mostContextsLimit]
+	}
+	return r
+}
+
+/* -------------------------------------------------------
+ * nodeName
+ * Fully qualified name of a function or interface node,
+ * matching the keys of ReachableFuncNames.
+ * ------------------------------------------------------- */
+func nodeName(n *cs_callgraph.Node) string {
+	if n.IfaceMethod != nil {
+		return n.IfaceMethod.FullName()
+	}
+	if n.Func == nil {
+		return "<root>"
+	}
+	return n.Func.String()
+}
@@ -0,0 +1,91 @@
+package stats
+
+import (
+	cs_callgraph "callstat/CS-Callgraph"
+	"sort"
+)
+
+/* ============================================================================
+ * CycleReport
+ * ----------------------------------------------------------------------------
+ * Recursion in the call graph: its strongly connected components with at
+ * least one in-depth member.
+ *
+ *   Count          number of cycles (non-trivial SCCs)
+ *   SelfRecursive  single functions calling themselves
+ *   Mutual         SCCs of two or more functions
+ *   CrossPackage   SCCs spanning more than one package
+ *   LargestSize    member count of the largest SCC
+ *   Cycles         every cycle, largest first
+ * ============================================================================
+ */
+type CycleReport struct {
+	Count         int      `json:"count"`
+	SelfRecursive int      `json:"selfRecursive"`
+	Mutual        int      `json:"mutual"`
+	CrossPackage  int      `json:"crossPackage"`
+	LargestSize   int      `json:"largestSize"`
+	Cycles        []*Cycle `json:"cycles"`
+}
+
+/* ============================================================================
+ * Cycle
+ * ----------------------------------------------------------------------------
+ *   ID         index in the order of cs_callgraph.Graph.Cycles, shared
+ *              with the "cycles" graph in the HTML report
+ *   Size       number of member functions
+ *   Functions  fully qualified member names
+ *   Packages   packages the members belong to
+ * ============================================================================
+ */
+type Cycle struct {
+	ID        int      `json:"id"`
+	Size      int      `json:"size"`
+	Functions []string `json:"functions"`
+	Packages  []string `json:"packages"`
+}
+
+/* ============================================================================
+ * gatherCycles
+ * ----------------------------------------------------------------------------
+ * Builds the CycleReport from g's SCCs, keeping those that touch an
+ * in-depth package. Context nodes are listed by their qualified name, so
+ * on an expanded graph one function can appear in several cycles.
+ * ============================================================================
+ */
+func gatherCycles(g *cs_callgraph.Graph, inDepth func(string) bool) *CycleReport {
+	report := &CycleReport{Cycles: []*Cycle{}}
+
+	for id, scc := range g.Cycles() {
+		c := &Cycle{ID: id, Size: len(scc), Functions: []string{}, Packages: []string{}}
+		pkgs    := make(map[string]struct{})
+		touches := false
+		for _, n := range scc {
+			c.Functions = append(c.Functions, n.QualifiedName())
+			if pkgPath, ok := nodePkgPath(n); ok {
+				pkgs[pkgPath] = struct{}{}
+				touches = touches || inDepth(pkgPath)
+			}
+		}
+		if !touches {
+			continue
+		}
+		for pkgPath := range pkgs {
+			c.Packages = append(c.Packages, pkgPath)
+		}
+		sort.Strings(c.Packages)
+
+		report.Count++
+		if c.Size == 1 {
+			report.SelfRecursive++
+		} else {
+			report.Mutual++
+		}
+		if len(c.Packages) > 1 {
+			report.CrossPackage++
+		}
+		report.LargestSize = max(report.LargestSize, c.Size)
+		report.Cycles = append(report.Cycles, c)
+	}
+	return report
+}
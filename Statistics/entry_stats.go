@@ -0,0 +1,137 @@
+package stats
+
+import (
+	cs_callgraph "callstat/CS-Callgraph"
+	"sort"
+)
+
+/* ============================================================================
+ * EntryReachability
+ * ----------------------------------------------------------------------------
+ * Reachability of a single entry point when several are analysed together.
+ *
+ *   Entry       fully qualified name of the entry function
+ *   Reachable   in-depth functions reachable from this entry
+ *   Exclusive   of those, how many no other entry reaches
+ *   SharedWith  functions shared with each other entry (omitted when there
+ *               are more than sharedWithLimit entries)
+ * ============================================================================
+ */
+type EntryReachability struct {
+	Entry      string         `json:"entry"`
+	Reachable  int            `json:"reachable"`
+	Exclusive  int            `json:"exclusive"`
+	SharedWith map[string]int `json:"sharedWith,omitempty"`
+}
+
+// Above this many entries the pairwise SharedWith table is skipped
+const sharedWithLimit = 32
+
+/* ============================================================================
+ * gatherEntryReachability
+ * ----------------------------------------------------------------------------
+ * Computes an EntryReachability per entry node. Returns nil for fewer than
+ * two entries - the aggregate report already covers a single root.
+ * ============================================================================
+ */
+func gatherEntryReachability(
+	entries []*cs_callgraph.Node,
+	inDepth func(string) bool,
+) []*EntryReachability {
+	if len(entries) < 2 {
+		return nil
+	}
+
+	sets := make([]map[int]struct{}, len(entries))
+	owners := make(map[int]int)
+	for i, n := range entries {
+		sets[i] = reachableFrom(n, inDepth)
+		for id := range sets[i] {
+			owners[id]++
+		}
+	}
+
+	var out []*EntryReachability
+	for i, n := range entries {
+		er := &EntryReachability{
+			Entry:     nodeName(n),
+			Reachable: len(sets[i]),
+		}
+		for id := range sets[i] {
+			if owners[id] == 1 {
+				er.Exclusive++
+			}
+		}
+		if len(entries) <= sharedWithLimit {
+			er.SharedWith = make(map[string]int)
+			for j, other := range entries {
+				if i == j {
+					continue
+				}
+				shared := 0
+				for id := range sets[i] {
+					if _, ok := sets[j][id]; ok {
+						shared++
+					}
+				}
+				er.SharedWith[nodeName(other)] = shared
+			}
+		}
+		out = append(out, er)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Entry < out[j].Entry
+	})
+	return out
+}
+
+/* -------------------------------------------------------
+ * reachableFrom
+ * IDs of in-depth nodes reachable from n. Out-of-depth
+ * nodes are walked through but not counted, as in
+ * traverseReachable.
+ * ------------------------------------------------------- */
+func reachableFrom(n *cs_callgraph.Node, inDepth func(string) bool) map[int]struct{} {
+	found := make(map[int]struct{})
+	walked := make(map[*cs_callgraph.Node]bool)
+	stack := []*cs_callgraph.Node{n}
+
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if cur == nil || walked[cur] {
+			continue
+		}
+		walked[cur] = true
+
+		pkgPath, ok := nodePkgPath(cur)
+		if !ok {
+			continue
+		}
+		if inDepth(pkgPath) {
+			found[cur.ID] = struct{}{}
+		}
+		for _, e := range cur.Out {
+			stack = append(stack, e.Callee)
+		}
+	}
+	return found
+}
+
+func nodePkgPath(n *cs_callgraph.Node) (string, bool) {
+	if n.IfaceMethod != nil {
+		if n.IfaceMethod.Pkg() == nil {
+			return "", false
+		}
+		return n.IfaceMethod.Pkg().Path(), true
+	}
+	if n.Func == nil {
+		return "", false
+	}
+	pkg := cs_callgraph.EffectivePkg(n.Func)
+	if pkg == nil || pkg.Pkg == nil {
+		return "", false
+	}
+	return pkg.Pkg.Path(), true
+}
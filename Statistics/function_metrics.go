@@ -0,0 +1,199 @@
+package stats
+
+import (
+	cs_callgraph "callstat/CS-Callgraph"
+	"go/token"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Functions at or above this cyclomatic complexity count as complex
+const complexThreshold = 10
+
+/* ============================================================================
+ * FunctionMetrics
+ * ----------------------------------------------------------------------------
+ * Size and shape of one in-depth function, joined with its reachability.
+ *
+ *   Function      fully qualified name (as in the export and queries)
+ *   Package       package path
+ *   Reachable     reachable from the entry points
+ *   Blocks        SSA basic blocks
+ *   Instructions  SSA instructions
+ *   Cyclomatic    1 + decision points of the SSA control-flow graph (each
+ *                 successor of a block beyond its first); 0 without a body
+ *   File          source file, Start/EndLine its declaration's line span,
+ *                 Lines = EndLine - StartLine + 1 (0 if there is no source)
+ *   Params        parameters, not counting the receiver
+ *   Exported … Wrapper  kind flags; Generic covers type-parameterised
+ *                 functions and their instantiations
+ *   Body          bodiless classification (asm, cgo, linkname, external)
+ * ============================================================================
+ */
+type FunctionMetrics struct {
+	Function     string `json:"function"`
+	Package      string `json:"package"`
+	Reachable    bool   `json:"reachable"`
+	Blocks       int    `json:"blocks"`
+	Instructions int    `json:"instructions"`
+	Cyclomatic   int    `json:"cyclomatic"`
+	File         string `json:"file,omitempty"`
+	StartLine    int    `json:"startLine,omitempty"`
+	EndLine      int    `json:"endLine,omitempty"`
+	Lines        int    `json:"lines"`
+	Params       int    `json:"params"`
+	Exported     bool   `json:"exported"`
+	Method       bool   `json:"method"`
+	Generic      bool   `json:"generic"`
+	Closure      bool   `json:"closure"`
+	Wrapper      bool   `json:"wrapper"`
+	Body         string `json:"body,omitempty"`
+}
+
+/* ============================================================================
+ * CodeSummary / CodeTotals
+ * ----------------------------------------------------------------------------
+ * FunctionMetrics summed over the reachable and the unreachable functions,
+ * e.g. "how much complex code is unreachable from main". Complex counts
+ * functions with Cyclomatic >= complexThreshold.
+ * ============================================================================
+ */
+type CodeSummary struct {
+	ComplexThreshold int        `json:"complexThreshold"`
+	Reachable        CodeTotals `json:"reachable"`
+	Unreachable      CodeTotals `json:"unreachable"`
+}
+
+type CodeTotals struct {
+	Functions    int `json:"functions"`
+	Instructions int `json:"instructions"`
+	Cyclomatic   int `json:"cyclomatic"`
+	Lines        int `json:"lines"`
+	Complex      int `json:"complex"`
+}
+
+func (t *CodeTotals) add(m *FunctionMetrics) {
+	t.Functions++
+	t.Instructions += m.Instructions
+	t.Cyclomatic   += m.Cyclomatic
+	t.Lines        += m.Lines
+	if m.Cyclomatic >= complexThreshold {
+		t.Complex++
+	}
+}
+
+/* ============================================================================
+ * Span
+ * ----------------------------------------------------------------------------
+ * Source extent of a function declaration or literal, keyed elsewhere by
+ * the ssa.Function's Pos (FuncDecl.Name / FuncLit.Type.Func).
+ * ============================================================================
+ */
+type Span struct {
+	Start token.Pos
+	End   token.Pos
+}
+
+/* ============================================================================
+ * GatherFunctionMetrics
+ * ----------------------------------------------------------------------------
+ * Fills r.Functions and r.Code for the in-depth functions of g. Must run
+ * after GatherCallGraphStats (it reads r.ReachableFuncNames). Context
+ * nodes of one function are measured once. spans resolves line extents;
+ * functions missing from it (synthetic ones) get no lines.
+ * ============================================================================
+ */
+func GatherFunctionMetrics(
+	g        *cs_callgraph.Graph,
+	r        *CallGraphReport,
+	fset     *token.FileSet,
+	spans    map[token.Pos]Span,
+	depthMap map[string]int,
+	maxDepth int,
+	skipPkg  map[string]struct{},
+) {
+	inDepth := makeDepthGate(depthMap, maxDepth, skipPkg)
+	seen    := make(map[*ssa.Function]struct{})
+	summary := &CodeSummary{ComplexThreshold: complexThreshold}
+	rows    := []*FunctionMetrics{}
+
+	for _, n := range g.FunctionNodes() {
+		fn := n.Func
+		if fn == nil {
+			continue
+		}
+		if _, dup := seen[fn]; dup {
+			continue
+		}
+		seen[fn] = struct{}{}
+		pkgPath, ok := nodePkgPath(n)
+		if !ok || !inDepth(pkgPath) {
+			continue
+		}
+
+		m := measure(fn, fset, spans)
+		m.Package = pkgPath
+		_, m.Reachable = r.ReachableFuncNames[fn.String()]
+		if n.Body != cs_callgraph.BodySource {
+			m.Body = n.Body.String()
+		}
+		rows = append(rows, m)
+
+		if m.Reachable {
+			summary.Reachable.add(m)
+		} else {
+			summary.Unreachable.add(m)
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Function < rows[j].Function })
+	r.Functions = rows
+	r.Code      = summary
+}
+
+/* -------------------------------------------------------
+ * measure
+ * Everything FunctionMetrics knows about fn alone.
+ * ------------------------------------------------------- */
+func measure(fn *ssa.Function, fset *token.FileSet, spans map[token.Pos]Span) *FunctionMetrics {
+	m := &FunctionMetrics{
+		Function: fn.String(),
+		Blocks:   len(fn.Blocks),
+		Params:   fn.Signature.Params().Len(),
+		Method:   fn.Signature.Recv() != nil,
+		Closure:  fn.Parent() != nil,
+		Wrapper:  fn.Synthetic != "" && fn.Synthetic != "package initializer",
+	}
+
+	origin := fn
+	if o := fn.Origin(); o != nil {
+		origin = o
+		m.Generic = o != fn
+	}
+	m.Generic  = m.Generic || origin.TypeParams().Len() > 0
+	m.Closure  = m.Closure || origin.Parent() != nil
+	m.Wrapper  = m.Wrapper && origin == fn
+	m.Exported = !m.Closure && token.IsExported(origin.Name())
+
+	for _, b := range fn.Blocks {
+		m.Instructions += len(b.Instrs)
+		if len(b.Succs) > 1 {
+			m.Cyclomatic += len(b.Succs) - 1
+		}
+	}
+	if len(fn.Blocks) > 0 {
+		m.Cyclomatic++
+	}
+
+	if span, ok := spans[origin.Pos()]; ok {
+		start, end := fset.Position(span.Start), fset.Position(span.End)
+		m.File      = start.Filename
+		m.StartLine = start.Line
+		m.EndLine   = end.Line
+		m.Lines     = end.Line - start.Line + 1
+	} else if pos := fset.Position(fn.Pos()); pos.IsValid() {
+		m.File = pos.Filename
+	}
+	return m
+}
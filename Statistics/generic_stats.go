@@ -0,0 +1,81 @@
+package stats
+
+import (
+	cs_callgraph "callstat/CS-Callgraph"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+/* ============================================================================
+ * GenericInstances
+ * ----------------------------------------------------------------------------
+ * Instantiations of one generic function (GenericsInstances mode only).
+ *
+ *   Function   fully qualified name of the generic origin
+ *   Instances  number of distinct instantiations in the graph
+ *   TypeArgs   type arguments of each instantiation, e.g. "[int, string]"
+ * ============================================================================
+ */
+type GenericInstances struct {
+	Function  string   `json:"function"`
+	Instances int      `json:"instances"`
+	TypeArgs  []string `json:"typeArgs"`
+}
+
+/* ============================================================================
+ * gatherGenericInstances
+ * ----------------------------------------------------------------------------
+ * Groups the in-depth instantiation nodes by origin via their InstanceEdges.
+ * Sorted by instance count, most instantiated first. Empty unless the graph
+ * was built with GenericsInstances.
+ * ============================================================================
+ */
+func gatherGenericInstances(
+	g       *cs_callgraph.Graph,
+	inDepth func(string) bool,
+) []*GenericInstances {
+	var out []*GenericInstances
+	listed := make(map[string]bool)
+
+	for _, n := range g.FunctionNodes() {
+		if n.Func == nil || listed[n.Func.String()] {
+			continue
+		}
+		pkgPath, ok := nodePkgPath(n)
+		if !ok || !inDepth(pkgPath) {
+			continue
+		}
+
+		qualifier := types.RelativeTo(cs_callgraph.EffectivePkg(n.Func).Pkg)
+		seenArgs  := make(map[string]bool)
+		gi := &GenericInstances{Function: n.Func.String()}
+		for _, inst := range n.Instances() {
+			args := make([]string, len(inst.Func.TypeArgs()))
+			for i, t := range inst.Func.TypeArgs() {
+				args[i] = types.TypeString(t, qualifier)
+			}
+			key := "[" + strings.Join(args, ", ") + "]"
+			if seenArgs[key] {
+				continue // another context of the same instance
+			}
+			seenArgs[key] = true
+			gi.TypeArgs = append(gi.TypeArgs, key)
+		}
+		if len(gi.TypeArgs) == 0 {
+			continue
+		}
+		listed[n.Func.String()] = true
+		sort.Strings(gi.TypeArgs)
+		gi.Instances = len(gi.TypeArgs)
+		out = append(out, gi)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Instances != out[j].Instances {
+			return out[i].Instances > out[j].Instances
+		}
+		return out[i].Function < out[j].Function
+	})
+	return out
+}
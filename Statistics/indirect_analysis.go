@@ -21,6 +21,13 @@ import (
  *   StaticCallSites      - callee known at compile time
  *   InterfaceCallSites   - dispatch through an interface method
  *   FuncVarCallSites     - call through a function-valued variable
+ *   ResolvedFuncVarCallSites
+ *                        - FuncVarCallSites with at least one outgoing edge
+ *                          in the graph (depends on the resolution mode)
+ *   ReflectCallSites     - reflect.Value.Call / CallSlice / Method /
+ *                          MethodByName (not counted as static)
+ *   ResolvedReflectCallSites
+ *                        - ReflectCallSites with at least one reflect edge
  *
  * Assignment / propagation counters:
  *   FuncLiteralStores    - `f := func() { ... }`  (closure/literal created)
@@ -44,6 +51,9 @@ type IndirectAnalysisReport struct {
 	StaticCallSites    		int `json:"staticCallSites"`
 	InterfaceCallSites 		int `json:"interfaceCallSites"`
 	FuncVarCallSites   		int `json:"funcVarCallSites"`
+	ResolvedFuncVarCallSites int `json:"resolvedFuncVarCallSites"`
+	ReflectCallSites   		int `json:"reflectCallSites"`
+	ResolvedReflectCallSites int `json:"resolvedReflectCallSites"`
 
 	// Assignment and propagation
 	FuncLiteralStores 		int `json:"funcLiteralStores"`
@@ -90,7 +100,8 @@ func (r *IndirectAnalysisReport) countPotential(v ssa.Value) {
 /* ============================================================================
  * GatherResearchStats
  * ----------------------------------------------------------------------------
- * Entry point. Starts a DFS from main and analyses every in-depth function.
+ * Entry point. Starts a DFS from every entry node (main, or the exported API
+ * in library mode) and analyses every in-depth function.
  * ============================================================================
  */
 func GatherResearchStats(
@@ -98,15 +109,16 @@ func GatherResearchStats(
 	depthMap    map[string]int,
 	maxDepth    int,
 	projectRoot string,
-	mainNode 	*cs_callgraph.Node,
+	entryNodes 	[]*cs_callgraph.Node,
 	skipPkg  map[string]struct{},
 ) *IndirectAnalysisReport {
 	report  := newIndirectReport()
 	inDepth := makeDepthGate(depthMap, maxDepth, skipPkg)
 
-	if mainNode != nil {
-		visited := make(map[int]struct{})
-		traverseAndAnalyze(mainNode, visited, report, inDepth)
+	visited  := make(map[int]struct{})
+	analysed := make(map[*ssa.Function]struct{})
+	for _, n := range entryNodes {
+		traverseAndAnalyze(n, visited, analysed, report, inDepth)
 	}
 
 	return report
@@ -116,16 +128,19 @@ func GatherResearchStats(
  * traverseAndAnalyze
  * ----------------------------------------------------------------------------
  * DFS over the call graph. Analyses instructions only for nodes whose
- * package passes the depth gate.
+ * package passes the depth gate, and each function only once: the context
+ * copies of an expanded graph share one body, so they are counted through
+ * their collapsed node (Base), whose edges cover every context.
  * ============================================================================
  */
 func traverseAndAnalyze(
-	n       *cs_callgraph.Node,
-	visited map[int]struct{},
-	r       *IndirectAnalysisReport,
-	inDepth func(string) bool,
+	n        *cs_callgraph.Node,
+	visited  map[int]struct{},
+	analysed map[*ssa.Function]struct{},
+	r        *IndirectAnalysisReport,
+	inDepth  func(string) bool,
 ) {
-	if n == nil || n.Func == nil {
+	if n == nil || (n.Func == nil && n.IfaceMethod == nil) {
 		return
 	}
 	if _, ok := visited[n.ID]; ok {
@@ -133,17 +148,75 @@ func traverseAndAnalyze(
 	}
 	visited[n.ID] = struct{}{}
 
-	pkg := cs_callgraph.EffectivePkg(n.Func)
-	if pkg != nil && pkg.Pkg != nil && inDepth(pkg.Pkg.Path()) {
-		analyzeInstructions(n.Func, r)
+	// Interface nodes have no body; they are only passed through to
+	// reach their dispatch targets.
+	if _, done := analysed[n.Func]; n.Func != nil && !done {
+		analysed[n.Func] = struct{}{}
+		sitesOf := n
+		if n.Base != nil {
+			sitesOf = n.Base
+		}
+		pkg := cs_callgraph.EffectivePkg(n.Func)
+		if pkg != nil && pkg.Pkg != nil && inDepth(pkg.Pkg.Path()) {
+			analyzeInstructions(n.Func, r, resolvedSites(sitesOf), reflectedSites(sitesOf))
+		}
 	}
 
 	for _, e := range n.Out {
 		if e.Callee != nil {
-			traverseAndAnalyze(e.Callee, visited, r, inDepth)
+			traverseAndAnalyze(e.Callee, visited, analysed, r, inDepth)
+		}
+	}
+}
+/* ============================================================================
+ * resolvedSites / reflectedSites
+ * ----------------------------------------------------------------------------
+ * resolvedSites returns the call sites in n that the graph resolved to a
+ * target: the *ssa.Call, *ssa.Go and *ssa.Defer sites of Call, Go and Defer
+ * edges respectively. Reference edges (e.g. the AssignEdge of a callback
+ * passed at a call) share the call's site but do not resolve it.
+ * reflectedSites narrows the out-edges to ReflectEdges instead - a
+ * reflective call always has an ordinary edge into package reflect as well.
+ * ============================================================================
+ */
+func resolvedSites(n *cs_callgraph.Node) map[ssa.Instruction]struct{} {
+	sites := make(map[ssa.Instruction]struct{})
+	for _, e := range n.Out {
+		for _, site := range e.Sites {
+			if callsAt(e.Kind, site) {
+				sites[site] = struct{}{}
+			}
+		}
+	}
+	return sites
+}
+
+// callsAt reports whether an edge of kind is the call made by site.
+func callsAt(kind cs_callgraph.EdgeKind, site ssa.Instruction) bool {
+	switch site.(type) {
+	case *ssa.Call:
+		return kind == cs_callgraph.CallEdge
+	case *ssa.Go:
+		return kind == cs_callgraph.GoEdge
+	case *ssa.Defer:
+		return kind == cs_callgraph.DeferEdge
+	}
+	return false
+}
+
+func reflectedSites(n *cs_callgraph.Node) map[ssa.Instruction]struct{} {
+	sites := make(map[ssa.Instruction]struct{})
+	for _, e := range n.Out {
+		if e.Kind != cs_callgraph.ReflectEdge {
+			continue
+		}
+		for _, site := range e.Sites {
+			sites[site] = struct{}{}
 		}
 	}
+	return sites
 }
+
 /* ============================================================================
  * analyzeInstructions
  * ----------------------------------------------------------------------------
@@ -156,7 +229,12 @@ func traverseAndAnalyze(
  *   BUCKET   - which counter(s) were incremented, or WHY it was skipped
  * ============================================================================
  */
-func analyzeInstructions(fn *ssa.Function, r *IndirectAnalysisReport) {
+func analyzeInstructions(
+	fn        *ssa.Function,
+	r         *IndirectAnalysisReport,
+	resolved  map[ssa.Instruction]struct{},
+	reflected map[ssa.Instruction]struct{},
+) {
 	for _, block := range fn.Blocks {
 		for _, instr := range block.Instrs {
 
@@ -185,13 +263,21 @@ func analyzeInstructions(fn *ssa.Function, r *IndirectAnalysisReport) {
 					break
 				}
 
-				if call.StaticCallee() != nil {
+				if cs_callgraph.IsReflectDispatch(call) {
+					r.ReflectCallSites++
+					if _, ok := reflected[instr]; ok {
+						r.ResolvedReflectCallSites++
+					}
+				} else if call.StaticCallee() != nil {
 					r.StaticCallSites++
 				} else if call.Method != nil {
 					r.InterfaceCallSites++
 				} else {
 					r.FuncVarCallSites++
 					r.getSig(sig).ActualCallSites++
+					if _, ok := resolved[instr]; ok {
+						r.ResolvedFuncVarCallSites++
+					}
 				}
 
 			case *ssa.Store:
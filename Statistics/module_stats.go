@@ -0,0 +1,145 @@
+package stats
+
+import (
+	cs_callgraph "callstat/CS-Callgraph"
+	"sort"
+)
+
+/* ============================================================================
+ * ModuleRef
+ * ----------------------------------------------------------------------------
+ * The Go module a package belongs to, as reported by the package loader.
+ *
+ *   Path     module path; "std" for the standard library
+ *   Version  selected version ("" for the main module and std)
+ *   Main     the module being analysed
+ *   Replace  replacement path (plus " version") when go.mod replaces it
+ * ============================================================================
+ */
+type ModuleRef struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	Main    bool   `json:"main,omitempty"`
+	Replace string `json:"replace,omitempty"`
+}
+
+/* ============================================================================
+ * ModuleStats
+ * ----------------------------------------------------------------------------
+ * How much of one module the analysed entry points use. Counts cover the
+ * in-depth packages of the module only, like PackageStats.
+ *
+ *   Packages            in-depth packages of the module
+ *   TotalFunctions      functions and interface methods in them
+ *   ReachableFunctions  of those, reachable from the entry points
+ *   ReachablePercent    ReachableFunctions / TotalFunctions * 100
+ *   EntryFunctions      reachable functions of the module called directly
+ *                       from another module - the API actually used
+ * ============================================================================
+ */
+type ModuleStats struct {
+	ModuleRef
+	Packages           int      `json:"packages"`
+	TotalFunctions     int      `json:"totalFunctions"`
+	ReachableFunctions int      `json:"reachableFunctions"`
+	ReachablePercent   float64  `json:"reachablePercent"`
+	EntryFunctions     []string `json:"entryFunctions"`
+}
+
+/* ============================================================================
+ * GatherModuleUtilisation
+ * ----------------------------------------------------------------------------
+ * Aggregates the in-depth functions of g by module, using modules (package
+ * path → module) and the reachability already recorded in r. Packages
+ * without a module (GOPATH mode) are left out. Sorted by TotalFunctions,
+ * largest first, so heavy and barely used dependencies stand out.
+ * ============================================================================
+ */
+func GatherModuleUtilisation(
+	g        *cs_callgraph.Graph,
+	r        *CallGraphReport,
+	modules  map[string]*ModuleRef,
+	depthMap map[string]int,
+	maxDepth int,
+	skipPkg  map[string]struct{},
+) []*ModuleStats {
+	inDepth := makeDepthGate(depthMap, maxDepth, skipPkg)
+	byPath  := make(map[string]*ModuleStats)
+	pkgs    := make(map[string]struct{})
+	counted := make(map[string]struct{})
+	entries := make(map[*ModuleStats]map[string]struct{})
+
+	moduleOf := func(n *cs_callgraph.Node) *ModuleStats {
+		pkgPath, ok := nodePkgPath(n)
+		if !ok || !inDepth(pkgPath) {
+			return nil
+		}
+		ref, ok := modules[pkgPath]
+		if !ok {
+			return nil
+		}
+		ms, ok := byPath[ref.Path]
+		if !ok {
+			ms = &ModuleStats{ModuleRef: *ref, EntryFunctions: []string{}}
+			byPath[ref.Path] = ms
+			entries[ms] = make(map[string]struct{})
+		}
+		if _, seen := pkgs[pkgPath]; !seen {
+			pkgs[pkgPath] = struct{}{}
+			ms.Packages++
+		}
+		return ms
+	}
+
+	nodes := append(g.FunctionNodes(), g.InterfaceNodes()...)
+	for _, n := range nodes {
+		if n.Func == nil && n.IfaceMethod == nil {
+			continue
+		}
+		ms := moduleOf(n)
+		if ms == nil {
+			continue
+		}
+
+		// Context nodes of one function count once
+		name := nodeName(n)
+		_, reachable := r.ReachableFuncNames[name]
+		if _, dup := counted[name]; !dup {
+			counted[name] = struct{}{}
+			ms.TotalFunctions++
+			if reachable {
+				ms.ReachableFunctions++
+			}
+		}
+		if !reachable {
+			continue
+		}
+
+		for _, e := range n.Out {
+			callee := moduleOf(e.Callee)
+			if callee == nil || callee == ms {
+				continue
+			}
+			entries[callee][nodeName(e.Callee)] = struct{}{}
+		}
+	}
+
+	out := make([]*ModuleStats, 0, len(byPath))
+	for _, ms := range byPath {
+		if ms.TotalFunctions > 0 {
+			ms.ReachablePercent = float64(ms.ReachableFunctions) / float64(ms.TotalFunctions) * 100
+		}
+		for name := range entries[ms] {
+			ms.EntryFunctions = append(ms.EntryFunctions, name)
+		}
+		sort.Strings(ms.EntryFunctions)
+		out = append(out, ms)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TotalFunctions != out[j].TotalFunctions {
+			return out[i].TotalFunctions > out[j].TotalFunctions
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
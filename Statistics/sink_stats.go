@@ -0,0 +1,162 @@
+package stats
+
+import (
+	cs_callgraph "callstat/CS-Callgraph"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+/* ============================================================================
+ * SinkReport
+ * ----------------------------------------------------------------------------
+ * Which entry points can reach the functions marked as sinks.
+ *
+ *   Patterns   sink patterns as given (function names or packages)
+ *   Reached    number of sinks reachable from at least one entry
+ *   Sinks      every sink function in the graph, reached ones first
+ * ============================================================================
+ */
+type SinkReport struct {
+	Patterns []string     `json:"patterns"`
+	Reached  int          `json:"reached"`
+	Sinks    []*SinkReach `json:"sinks"`
+}
+
+/* ============================================================================
+ * SinkReach
+ * ----------------------------------------------------------------------------
+ *   Sink     fully qualified name of the sink function
+ *   Package  its package path
+ *   Entries  one witness per entry point that reaches the sink (empty if
+ *            the sink is in the graph but unreachable)
+ * ============================================================================
+ */
+type SinkReach struct {
+	Sink    string         `json:"sink"`
+	Package string         `json:"package"`
+	Entries []*SinkWitness `json:"entries"`
+}
+
+/* ============================================================================
+ * SinkWitness
+ * ----------------------------------------------------------------------------
+ * A shortest call chain from Entry to the sink. Chain starts with the entry
+ * and ends with the sink; every step after the first names the edge kind
+ * and first call site that leads into it.
+ * ============================================================================
+ */
+type SinkWitness struct {
+	Entry string         `json:"entry"`
+	Chain []*WitnessStep `json:"chain"`
+}
+
+type WitnessStep struct {
+	Function string `json:"function"`
+	Kind     string `json:"kind,omitempty"`
+	Site     string `json:"site,omitempty"`
+}
+
+/* ============================================================================
+ * GatherSinkReachability
+ * ----------------------------------------------------------------------------
+ * Walks Node.In edges backwards from every node marked by MarkSinks and
+ * records each entry point it meets, with the shortest chain as witness.
+ * Context nodes of the same sink function (expanded view) are reported as
+ * one sink. Returns nil when no patterns were given.
+ * ============================================================================
+ */
+func GatherSinkReachability(g *cs_callgraph.Graph, patterns []string) *SinkReport {
+	if len(patterns) == 0 {
+		return nil
+	}
+	report := &SinkReport{Patterns: patterns, Sinks: []*SinkReach{}}
+
+	entries := g.Entries()
+	bySink  := make(map[string]*SinkReach)
+	var order []string
+
+	nodes := append(g.FunctionNodes(), g.InterfaceNodes()...)
+	for _, sink := range nodes {
+		if !sink.Sink {
+			continue
+		}
+		name := nodeName(sink)
+		sr, ok := bySink[name]
+		if !ok {
+			pkgPath, _ := nodePkgPath(sink)
+			sr = &SinkReach{Sink: name, Package: pkgPath, Entries: []*SinkWitness{}}
+			bySink[name] = sr
+			order = append(order, name)
+		}
+
+		// Callers yields a BFS tree: each hop's Edge leads one step closer
+		hops := make(map[*cs_callgraph.Node]cs_callgraph.Hop)
+		for _, h := range cs_callgraph.Callers(sink, -1) {
+			hops[h.Node] = h
+		}
+		for _, entry := range entries {
+			if hasWitness(sr, entry) {
+				continue
+			}
+			if _, reached := hops[entry]; !reached && entry != sink {
+				continue
+			}
+			sr.Entries = append(sr.Entries, &SinkWitness{
+				Entry: nodeName(entry),
+				Chain: witnessChain(entry, sink, hops),
+			})
+		}
+	}
+
+	for _, name := range order {
+		sr := bySink[name]
+		sort.Slice(sr.Entries, func(i, j int) bool {
+			return sr.Entries[i].Entry < sr.Entries[j].Entry
+		})
+		if len(sr.Entries) > 0 {
+			report.Reached++
+		}
+		report.Sinks = append(report.Sinks, sr)
+	}
+	sort.SliceStable(report.Sinks, func(i, j int) bool {
+		ri, rj := len(report.Sinks[i].Entries) > 0, len(report.Sinks[j].Entries) > 0
+		if ri != rj {
+			return ri
+		}
+		return report.Sinks[i].Sink < report.Sinks[j].Sink
+	})
+	return report
+}
+
+/* -------------------------------------------------------
+ * witnessChain
+ * Follows the BFS tree from entry down to sink.
+ * ------------------------------------------------------- */
+func witnessChain(
+	entry *cs_callgraph.Node,
+	sink  *cs_callgraph.Node,
+	hops  map[*cs_callgraph.Node]cs_callgraph.Hop,
+) []*WitnessStep {
+	chain := []*WitnessStep{{Function: entry.QualifiedName()}}
+	for at := entry; at != sink; {
+		e := hops[at].Edge
+		step := &WitnessStep{Function: e.Callee.QualifiedName(), Kind: e.Kind.String()}
+		if pos := e.Positions(); len(pos) > 0 {
+			step.Site = fmt.Sprintf("%s:%d", filepath.Base(pos[0].Filename), pos[0].Line)
+		}
+		chain = append(chain, step)
+		at = e.Callee
+	}
+	return chain
+}
+
+func hasWitness(sr *SinkReach, entry *cs_callgraph.Node) bool {
+	name := nodeName(entry)
+	for _, w := range sr.Entries {
+		if w.Entry == name {
+			return true
+		}
+	}
+	return false
+}
@@ -2,36 +2,54 @@ package stats
 
 import (
 	cs_callgraph "callstat/CS-Callgraph"
+	rules "callstat/Rules"
 	"encoding/json"
+	"go/token"
 	"os"
 	"path/filepath"
-
-	"golang.org/x/tools/go/ssa"
 )
 
 /* ============================================================================
  * EdgeKindCounts
  * ----------------------------------------------------------------------------
- * Stores the count of each edge type for a package.
+ * Stores the count of each edge type for a package, along with how many
+ * edges each resolution analysis contributed.
  * ============================================================================
  */
 type EdgeKindCounts struct {
-	Counts map[string]int `json:"counts"`
-	Total  int            `json:"total"`
+	Counts     map[string]int `json:"counts"`
+	Provenance map[string]int `json:"provenance"`
+	Total      int            `json:"total"`
 }
 
 func newEdgeKindCounts() *EdgeKindCounts {
 	return &EdgeKindCounts{
-		Counts: make(map[string]int),
-		Total:  0,
+		Counts:     make(map[string]int),
+		Provenance: make(map[string]int),
+		Total:      0,
 	}
 }
 
-func (e *EdgeKindCounts) add(kind cs_callgraph.EdgeKind) {
-	e.Counts[kind.String()]++
+func (e *EdgeKindCounts) add(edge *cs_callgraph.Edge) {
+	e.Counts[edge.Kind.String()]++
+	e.Provenance[edge.Prov.String()]++
 	e.Total++
 }
 
+/* ============================================================================
+ * ResolutionInfo
+ * ----------------------------------------------------------------------------
+ * Records which resolution modes built the graph and how long it took, so
+ * reports from different modes on the same project can be compared.
+ * ============================================================================
+ */
+type ResolutionInfo struct {
+	Iface       string `json:"iface"`
+	FuncValue   string `json:"funcValue"`
+	Generics    string `json:"generics"`
+	BuildMillis int64  `json:"buildMillis"`
+}
+
 
 /* ============================================================================
  * CallGraphStats
@@ -41,12 +59,14 @@ func (e *EdgeKindCounts) add(kind cs_callgraph.EdgeKind) {
  * ============================================================================
  */
 type PackageStats struct {
-	Path            string          `json:"path"`
-	Depth           int             `json:"depth"`
-	IsStdlib        bool            `json:"isStdlib"`
-	FunctionCount   int             `json:"functionCount"`
-	UnusedFunctions []string        `json:"unusedFunctions"`
-	Edges           *EdgeKindCounts `json:"edges"`
+	Path            string              `json:"path"`
+	Depth           int                 `json:"depth"`
+	IsStdlib        bool                `json:"isStdlib"`
+	FunctionCount   int                 `json:"functionCount"`
+	UnusedFunctions []string            `json:"unusedFunctions"`
+	Unused          []*FunctionLocation `json:"unused"` // UnusedFunctions, qualified and located
+	Edges           *EdgeKindCounts     `json:"edges"`
+	Bodiless        map[string]int      `json:"bodiless,omitempty"` // BodyKind → functions without a scanned body
 }
 
 func newPackageStats(path string, depth int) *PackageStats {
@@ -54,10 +74,52 @@ func newPackageStats(path string, depth int) *PackageStats {
 		Path            : path,
 		Depth           : depth,
 		UnusedFunctions : []string{},
+		Unused          : []*FunctionLocation{},
 		Edges           : newEdgeKindCounts(),
 	}
 }
 
+/* ============================================================================
+ * FunctionLocation
+ * ----------------------------------------------------------------------------
+ * A function by its fully qualified name (methods as "(*pkg.T).M") and the
+ * position of its declaration. File is "" for functions without source,
+ * such as synthetic wrappers.
+ * ============================================================================
+ */
+type FunctionLocation struct {
+	Function string `json:"function"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+func locate(name string, pos token.Position) *FunctionLocation {
+	loc := &FunctionLocation{Function: name}
+	if pos.IsValid() {
+		loc.File, loc.Line, loc.Column = pos.Filename, pos.Line, pos.Column
+	}
+	return loc
+}
+
+/* ============================================================================
+ * NodeLocation
+ * ----------------------------------------------------------------------------
+ * Returns the FunctionLocation of a function or interface method node,
+ * named by its QualifiedName. fset resolves interface method positions
+ * (functions carry their program's own).
+ * ============================================================================
+ */
+func NodeLocation(n *cs_callgraph.Node, fset *token.FileSet) *FunctionLocation {
+	switch {
+	case n.Func != nil:
+		return locate(n.QualifiedName(), n.Func.Prog.Fset.Position(n.Func.Pos()))
+	case n.IfaceMethod != nil && fset != nil:
+		return locate(n.QualifiedName(), fset.Position(n.IfaceMethod.Pos()))
+	}
+	return &FunctionLocation{Function: n.QualifiedName()}
+}
+
 /* ============================================================================
  * CallGraphReport
  * ----------------------------------------------------------------------------
@@ -65,6 +127,8 @@ func newPackageStats(path string, depth int) *PackageStats {
  * ============================================================================
  */
 type CallGraphReport struct {
+	Mode               string                   `json:"mode"`
+	EntryPoints        []string                 `json:"entryPoints"`
 	TotalFunctions     int                      `json:"totalFunctions"`
 	ReachableFunctions int                      `json:"reachableFunctions"`
 	MaxDepthSpecified  int                      `json:"maxDepthSpecified"`
@@ -72,17 +136,31 @@ type CallGraphReport struct {
 	Packages           map[string]*PackageStats `json:"packages"`
 
 	Indirect           *IndirectAnalysisReport	`json:"indirect"`
+	Resolution         *ResolutionInfo          `json:"resolution,omitempty"`
+	Contexts           *ContextReport           `json:"contexts,omitempty"`
+	EntryReach         []*EntryReachability     `json:"entryReachability,omitempty"`
+	Generics           []*GenericInstances      `json:"generics,omitempty"`
+	Sinks              *SinkReport              `json:"sinks,omitempty"`
+	Modules            []*ModuleStats           `json:"modules,omitempty"`
+	Cycles             *CycleReport             `json:"cycles"`
+	Centrality         []*FunctionCentrality    `json:"centrality,omitempty"`
+	Code               *CodeSummary             `json:"code,omitempty"`
+	Functions          []*FunctionMetrics       `json:"functions,omitempty"`
+	Rules              *rules.Report            `json:"rules,omitempty"`
 
 	ReachableFuncNames map[string]struct{}      `json:"-"`
+
+	std                cs_callgraph.STDLib      // Marks Packages[*].IsStdlib
 }
 
-func newCallGraphReport(maxDepth int) *CallGraphReport {
+func newCallGraphReport(maxDepth int, std cs_callgraph.STDLib) *CallGraphReport {
     return &CallGraphReport{
         MaxDepthSpecified:  maxDepth,
         GrandTotalEdges:    newEdgeKindCounts(),
         Packages:           make(map[string]*PackageStats),
         ReachableFuncNames: make(map[string]struct{}),
         Indirect:           newIndirectReport(),
+        std:                std,
     }
 }
 
@@ -98,7 +176,7 @@ func (r *CallGraphReport) getPkg(
 		d = depth
 	}
 	pkg := newPackageStats(path, d)
-	pkg.IsStdlib = cs_callgraph.IsStdlib(path)
+	pkg.IsStdlib = r.std.Contains(path)
 	r.Packages[path] = pkg
 	return pkg
 }
@@ -144,6 +222,9 @@ func (r *CallGraphReport) WriteJSONToFile(filename string) error {
  * GatherCallGraphStats
  * ----------------------------------------------------------------------------
  * Traverses the callgraph and gathers stats scoped to packages within depth.
+ * Reachability is measured from the entry points the graph's Root fans out
+ * to - main(s), package inits, explicit roots, or the exported API in
+ * library mode.
  * ============================================================================
  */
 func GatherCallGraphStats(
@@ -151,25 +232,30 @@ func GatherCallGraphStats(
     depthMap    map[string]int,
     maxDepth    int,
     projectRoot string,
-    main        *ssa.Function,
 	skipPkg     map[string]struct{},
 ) *CallGraphReport {
-    report := newCallGraphReport(maxDepth)
+    report := newCallGraphReport(maxDepth, g.Std)
     inDepth := makeDepthGate(depthMap, maxDepth, skipPkg)
 
     countFunctions(g, report, depthMap, inDepth)
     countEdges(g, report, depthMap, inDepth)
 
-    mainNode := g.Nodes[main]
+    entryNodes := g.Entries()
+    for _, n := range entryNodes {
+        report.EntryPoints = append(report.EntryPoints, nodeName(n))
+    }
 
-    if mainNode != nil {
-        visited := make(map[int]struct{})
-        traverseReachable(mainNode, visited, report, inDepth)
+    visited := make(map[int]struct{})
+    for _, n := range entryNodes {
+        traverseReachable(n, visited, report, inDepth)
     }
+    report.EntryReach = gatherEntryReachability(entryNodes, inDepth)
+    report.Generics = gatherGenericInstances(g, inDepth)
+    report.Cycles = gatherCycles(g, inDepth)
 
     collectUnused(g, report, depthMap, inDepth)
 	report.Indirect = GatherResearchStats(
-		g, depthMap, maxDepth, projectRoot, mainNode, skipPkg,
+		g, depthMap, maxDepth, projectRoot, entryNodes, skipPkg,
 	);
     return report
 }
@@ -207,7 +293,7 @@ func countFunctions(
     g           *cs_callgraph.Graph , r         *CallGraphReport, 
     depthMap    map[string]int      , inDepth   func(string) bool,
 ) {
-	for _, n := range g.Nodes {
+	for _, n := range g.FunctionNodes() {
 		if n.Func == nil {
 			continue
 		}
@@ -220,9 +306,16 @@ func countFunctions(
 			continue
 		}
 		r.TotalFunctions++
-		r.getPkg(pkgPath, depthMap).FunctionCount++
+		p := r.getPkg(pkgPath, depthMap)
+		p.FunctionCount++
+		if n.Body != cs_callgraph.BodySource {
+			if p.Bodiless == nil {
+				p.Bodiless = make(map[string]int)
+			}
+			p.Bodiless[n.Body.String()]++
+		}
 	}
-	for _, n := range g.IfaceNodes {
+	for _, n := range g.InterfaceNodes() {
 		if n.IfaceMethod.Pkg() == nil {
 			continue
 		}
@@ -246,7 +339,7 @@ func countEdges(
     g           *cs_callgraph.Graph , r         *CallGraphReport, 
     depthMap    map[string]int      , inDepth   func(string) bool,
 ) {
-	for _, n := range g.Nodes {
+	for _, n := range g.FunctionNodes() {
 		if n.Func == nil {
 			continue
 		}
@@ -264,15 +357,15 @@ func countEdges(
 				continue
 			}
 			p := r.getPkg(callerPath, depthMap)
-			p.Edges.add(e.Kind)
-			r.GrandTotalEdges.add(e.Kind)
+			p.Edges.add(e)
+			r.GrandTotalEdges.add(e)
 		}
 	}
 	/* -------------------------------------------------------
      * Interface method nodes: count their outgoing edges
-     * (interface → concrete impl edges added during visit)
+     * (interface → concrete impl dispatch edges)
      * ------------------------------------------------------- */
-    for _, n := range g.IfaceNodes {
+    for _, n := range g.InterfaceNodes() {
 		if n.IfaceMethod.Pkg() == nil {
 			continue
 		}
@@ -285,8 +378,8 @@ func countEdges(
                 continue
             }
             p := r.getPkg(pkgPath, depthMap)
-            p.Edges.add(e.Kind)
-            r.GrandTotalEdges.add(e.Kind)
+            p.Edges.add(e)
+            r.GrandTotalEdges.add(e)
         }
     }
 }
@@ -318,7 +411,8 @@ func edgeCalleeInDepth(e *cs_callgraph.Edge, inDepth func(string) bool) bool {
  * collectUnused
  * ----------------------------------------------------------------------------
  * Identifies functions that were not reached during traversal from main,
- * grouped by package. Only considers in-depth packages.
+ * grouped by package. Only considers in-depth packages. In a context-expanded
+ * graph several nodes share a function, so each name is listed once.
  * ============================================================================
  */
 func collectUnused(
@@ -327,10 +421,14 @@ func collectUnused(
     depthMap map[string]int,
     inDepth  func(string) bool,
 ) {
-    for _, n := range g.Nodes {
+    listed := make(map[string]struct{})
+    var fset *token.FileSet // for interface method positions
+
+    for _, n := range g.FunctionNodes() {
         if n.Func == nil {
             continue
         }
+        fset = n.Func.Prog.Fset
         pkg := cs_callgraph.EffectivePkg(n.Func)
         if pkg == nil || pkg.Pkg == nil {
             continue
@@ -338,16 +436,19 @@ func collectUnused(
         if !inDepth(pkg.Pkg.Path()) {
             continue
         }
+        if _, dup := listed[n.Func.String()]; dup {
+            continue
+        }
+        listed[n.Func.String()] = struct{}{}
         if _, reachable := r.ReachableFuncNames[n.Func.String()]; !reachable {
-            r.getPkg(pkg.Pkg.Path(), depthMap).UnusedFunctions = append(
-                r.getPkg(pkg.Pkg.Path(), depthMap).UnusedFunctions,
-                n.Func.Name(),
-            )
+            ps := r.getPkg(pkg.Pkg.Path(), depthMap)
+            ps.UnusedFunctions = append(ps.UnusedFunctions, n.Func.Name())
+            ps.Unused = append(ps.Unused, locate(n.Func.String(), n.Func.Prog.Fset.Position(n.Func.Pos())))
         }
     }
 
     // Interface nodes — same criterion as traverseReachable
-    for _, n := range g.IfaceNodes {
+    for _, n := range g.InterfaceNodes() {
         if n.IfaceMethod.Pkg() == nil {
             continue
         }
@@ -355,11 +456,14 @@ func collectUnused(
         if !inDepth(pkgPath) {
             continue
         }
+        if _, dup := listed[n.IfaceMethod.FullName()]; dup {
+            continue
+        }
+        listed[n.IfaceMethod.FullName()] = struct{}{}
         if _, reachable := r.ReachableFuncNames[n.IfaceMethod.FullName()]; !reachable {
-            r.getPkg(pkgPath, depthMap).UnusedFunctions = append(
-                r.getPkg(pkgPath, depthMap).UnusedFunctions,
-                n.IfaceMethod.FullName(),
-            )
+            ps := r.getPkg(pkgPath, depthMap)
+            ps.UnusedFunctions = append(ps.UnusedFunctions, n.IfaceMethod.FullName())
+            ps.Unused = append(ps.Unused, NodeLocation(n, fset))
         }
     }
 }
@@ -38,7 +38,8 @@ func isAnonFunc(fn *cs_callgraph.Node) bool {
 /* ============================================================================
  * BuildDotGraphPerPackage
  * ----------------------------------------------------------------------------
- * Traverses the callgraph and builds a DOT graph per package.
+ * Traverses the callgraph and builds a DOT graph per package. For a
+ * context-expanded graph every (function, context) node is drawn separately.
  * Each package gets its own DotGraph containing:
  *   - Local nodes
  *   - Edges within the package
@@ -48,11 +49,12 @@ func isAnonFunc(fn *cs_callgraph.Node) bool {
 func BuildDotGraphPerPackage(
 	g 			*cs_callgraph.Graph, 
     skipPkg 	map[string]struct{},  
+    styles  	*StyleConfig,
 ) map[string]*DotGraph {
 
 	packageGraphs := map[string]*DotGraph{}
 
-	for _, n := range g.Nodes {
+	for _, n := range g.FunctionNodes() {
 
 		/* -------------------------------------------------------
 		 * 1. VALIDATION
@@ -75,7 +77,7 @@ func BuildDotGraphPerPackage(
 		/* -------------------------------------------------------
 		 * 2. GRAPH INITIALIZATION
 		 * ------------------------------------------------------- */
-		pkgGraph := ensurePackageGraph(packageGraphs, pkgPath)
+		pkgGraph := ensurePackageGraph(packageGraphs, pkgPath, styles)
 
 		/* -------------------------------------------------------
 		 * 3. LOCAL NODE REGISTRATION
@@ -89,9 +91,9 @@ func BuildDotGraphPerPackage(
 	}
 	/* -------------------------------------------------------
      * Interface method nodes: seed into their own package graph
-     * and register any incoming edges from concrete callers.
+     * and emit their dispatch edges to concrete implementations.
      * ------------------------------------------------------- */
-    for _, n := range g.IfaceNodes {
+    for _, n := range g.InterfaceNodes() {
 		if n.IfaceMethod.Pkg() == nil {
 			continue
 		}
@@ -99,11 +101,58 @@ func BuildDotGraphPerPackage(
         if _, skip := skipPkg[pkgPath]; skip {
             continue
         }
-        pkgGraph := ensurePackageGraph(packageGraphs, pkgPath)
+        pkgGraph := ensurePackageGraph(packageGraphs, pkgPath, styles)
         registerIfaceNode(pkgGraph, n)
+        handleEdges(pkgGraph, n, pkgPath)
     }
+	groupInstances(packageGraphs, g)
 	return packageGraphs
 }
+
+/* ============================================================================
+ * groupInstances
+ * ----------------------------------------------------------------------------
+ * Moves every generic origin that has instantiation nodes into a cluster
+ * of its own, together with those instances. Instances always live in the
+ * origin's package, so only that package graph is touched.
+ * ============================================================================
+ */
+func groupInstances(packageGraphs map[string]*DotGraph, g *cs_callgraph.Graph) {
+	for _, origin := range g.FunctionNodes() {
+		instances := origin.Instances()
+		if origin.Func == nil || len(instances) == 0 {
+			continue
+		}
+		pkg := cs_callgraph.EffectivePkg(origin.Func)
+		if pkg == nil || pkg.Pkg == nil {
+			continue
+		}
+		pkgGraph, ok := packageGraphs[pkg.Pkg.Path()]
+		if !ok {
+			continue
+		}
+
+		clusterID := "cluster_generic_" + dotNodeID(origin)
+		attrs := map[string]string{"tooltip": fullFuncName(origin)}
+		maps.Copy(attrs, pkgGraph.styles.Cluster)
+		cluster := &DotCluster{
+			ID:    clusterID,
+			Label: shortFuncName(origin) + " (generic)",
+			Attrs: attrs,
+			Nodes: make(map[string]*DotNode),
+		}
+		for _, n := range append([]*cs_callgraph.Node{origin}, instances...) {
+			id := dotNodeID(n)
+			if dn, ok := pkgGraph.Nodes[id]; ok {
+				cluster.Nodes[id] = dn
+				delete(pkgGraph.Nodes, id)
+			}
+		}
+		if len(cluster.Nodes) > 0 {
+			pkgGraph.Clusters[clusterID] = cluster
+		}
+	}
+}
 /* ============================================================================
  * registerIfaceNode
  * ----------------------------------------------------------------------------
@@ -115,7 +164,7 @@ func registerIfaceNode(pkgGraph *DotGraph, n *cs_callgraph.Node) {
     if _, exists := pkgGraph.Nodes[nodeID]; exists {
         return
     }
-    pkgGraph.Nodes[nodeID] = buildNodeFromCS(n)
+    pkgGraph.Nodes[nodeID] = buildNodeFromCS(pkgGraph.styles, n)
 }
 /* ============================================================================
  * ensurePackageGraph
@@ -126,13 +175,14 @@ func registerIfaceNode(pkgGraph *DotGraph, n *cs_callgraph.Node) {
 func ensurePackageGraph(
 	graphs map[string]*DotGraph,
 	pkgPath string,
+	styles *StyleConfig,
 ) *DotGraph {
 
 	if g, ok := graphs[pkgPath]; ok {
 		return g
 	}
 
-	g := newDotGraph()
+	g := newDotGraph(styles)
 	graphs[pkgPath] = g
 	return g
 }
@@ -151,7 +201,7 @@ func registerLocalNode(pkgGraph *DotGraph, n *cs_callgraph.Node) {
 		return
 	}
 
-	pkgGraph.Nodes[nodeID] = buildNodeFromCS(n)
+	pkgGraph.Nodes[nodeID] = buildNodeFromCS(pkgGraph.styles, n)
 }
 
 /* ============================================================================
@@ -215,6 +265,9 @@ func handleEdge(
 		handlePanicEdge(pkgGraph, n, e)
 		return
 	}
+	if e.Kind == cs_callgraph.InstanceEdge {
+		return // drawn as a cluster by groupInstances
+	}
 
 	/* -------------------------------------------------------
 	 * 2. VALIDATION & LOGGING
@@ -271,16 +324,17 @@ func handlePanicEdge(
 
 	if _, exists := pkgGraph.Nodes[sinkID]; !exists {
 		pkgGraph.Nodes[sinkID] = buildNode(
+			pkgGraph.styles,
 			sinkID, sinkID,
 			sinkID, ns_panic,
 		)
 	}
 
-	pkgGraph.Edges = append(pkgGraph.Edges, buildEdge(
-		convertNodeID(n.ID, ns_normal),
+	pkgGraph.Edges = append(pkgGraph.Edges, buildEdgeForCS(
+		pkgGraph.styles,
+		dotNodeID(n),
 		sinkID,
-		mapEdgeKindToStyle(cs_callgraph.PanicEdge),
-		e.Description(),
+		e,
 	))
 }
 /* ============================================================================
@@ -306,13 +360,13 @@ func handleIfaceEdge(
          * just add the edge.
          * ------------------------------------------------------- */
         if _, exists := pkgGraph.Nodes[ifaceNodeID]; !exists {
-            pkgGraph.Nodes[ifaceNodeID] = buildNodeFromCS(e.Callee)
+            pkgGraph.Nodes[ifaceNodeID] = buildNodeFromCS(pkgGraph.styles, e.Callee)
         }
-        pkgGraph.Edges = append(pkgGraph.Edges, buildEdge(
-            convertNodeID(n.ID, ns_normal),
+        pkgGraph.Edges = append(pkgGraph.Edges, buildEdgeForCS(
+            pkgGraph.styles,
+            dotNodeID(n),
             ifaceNodeID,
-            mapEdgeKindToStyle(e.Kind),
-            e.Description(),
+            e,
         ))
         return
     }
@@ -323,17 +377,18 @@ func handleIfaceEdge(
     cluster := buildCluster(pkgGraph, &ifacePkg)
     if _, exists := cluster.Nodes[ifaceNodeID]; !exists {
         cluster.Nodes[ifaceNodeID] = buildNode(
+            pkgGraph.styles,
             ifaceNodeID,
-            e.Callee.IfaceMethod.Name(),
-            e.Callee.IfaceMethod.FullName(),
+            shortFuncName(e.Callee),
+            fullFuncName(e.Callee),
             ns_interface,
         )
     }
-    pkgGraph.Edges = append(pkgGraph.Edges, buildEdge(
-        convertNodeID(n.ID, ns_normal),
+    pkgGraph.Edges = append(pkgGraph.Edges, buildEdgeForCS(
+        pkgGraph.styles,
+        dotNodeID(n),
         ifaceNodeID,
-        mapEdgeKindToStyle(e.Kind),
-        e.Description(),
+        e,
     ))
 }
 /* ============================================================================
@@ -346,10 +401,10 @@ func handleRootEdge(pkgGraph *DotGraph, e *cs_callgraph.Edge) {
 	if e.Callee != nil {
 		rootID := convertNodeID(e.Callee.ID, ns_normal)
 		if _, exists := pkgGraph.Nodes[rootID]; !exists {
-			pkgGraph.Nodes[rootID] = buildNodeFromCS(e.Callee)
+			pkgGraph.Nodes[rootID] = buildNodeFromCS(pkgGraph.styles, e.Callee)
 		}
 	}
-	pkgGraph.Edges = append(pkgGraph.Edges, buildEdgeFromCS(e))
+	pkgGraph.Edges = append(pkgGraph.Edges, buildEdgeFromCS(pkgGraph.styles, e))
 }
 
 /* ============================================================================
@@ -359,7 +414,7 @@ func handleRootEdge(pkgGraph *DotGraph, e *cs_callgraph.Edge) {
  * ============================================================================
  */
 func handleIntraPackageEdge(pkgGraph *DotGraph, e *cs_callgraph.Edge) {
-	pkgGraph.Edges = append(pkgGraph.Edges, buildEdgeFromCS(e))
+	pkgGraph.Edges = append(pkgGraph.Edges, buildEdgeFromCS(pkgGraph.styles, e))
 }
 
 /* ============================================================================
@@ -391,17 +446,21 @@ func shortPkgName(pkgPath string) string {
  * shortFuncName
  * ----------------------------------------------------------------------------
  * Returns a short display name for a function. Falls back to "<root>" if
- * the function is nil. Used mainly for graph node labels.
+ * the function is nil. Used mainly for graph node labels. Context nodes get
+ * their innermost call site appended so siblings can be told apart.
  * ============================================================================
  */
 func shortFuncName(n *cs_callgraph.Node) string {
+    name := "<root>"
     if n.IfaceMethod != nil {
-        return n.IfaceMethod.Name()
+        name = n.IfaceMethod.Name()
+    } else if n.Func != nil {
+        name = n.Func.Name()
     }
-    if n.Func == nil {
-        return "<root>"
+    if site := n.InnermostSite(); site != "" {
+        name += "\n@" + site
     }
-    return n.Func.Name()
+    return name
 }
 
 /* ============================================================================
@@ -413,12 +472,12 @@ func shortFuncName(n *cs_callgraph.Node) string {
  */
 func fullFuncName(n *cs_callgraph.Node) string {
     if n.IfaceMethod != nil {
-        return n.IfaceMethod.FullName()
+        return n.IfaceMethod.FullName() + n.ContextString()
     }
     if n.Func == nil {
         return "<root>"
     }
-    return n.Func.String()
+    return n.Func.String() + n.ContextString()
 }
 
 /* ============================================================================
@@ -441,6 +500,20 @@ func convertNodeID(id int, typ NodeStyle) string {
 	}
 }
 
+/* ============================================================================
+ * dotNodeID
+ * ----------------------------------------------------------------------------
+ * Returns the local DOT identifier of a callgraph node, picking the interface
+ * prefix for interface method nodes so edges can originate from them too.
+ * ============================================================================
+ */
+func dotNodeID(n *cs_callgraph.Node) string {
+	if n.IfaceMethod != nil {
+		return convertNodeID(n.ID, ns_interface)
+	}
+	return convertNodeID(n.ID, ns_normal)
+}
+
 /* ============================================================================
  * buildNodeFromCS
  * ----------------------------------------------------------------------------
@@ -448,12 +521,13 @@ func convertNodeID(id int, typ NodeStyle) string {
  * and injects label + tooltip information.
  * ============================================================================
  */
-func buildNodeFromCS(n *cs_callgraph.Node) *DotNode {
+func buildNodeFromCS(st *StyleConfig, n *cs_callgraph.Node) *DotNode {
     if n.IfaceMethod != nil {
         return buildNode(
+            st,
             convertNodeID(n.ID, ns_interface),
-            n.IfaceMethod.Name(),
-            n.IfaceMethod.FullName(),
+            shortFuncName(n),
+            fullFuncName(n),
             ns_interface,
         )
     }
@@ -461,22 +535,65 @@ func buildNodeFromCS(n *cs_callgraph.Node) *DotNode {
     if isAnonFunc(n) {
         nodeType = ns_anon
     }
-    return buildNode(
+    tooltip := fullFuncName(n)
+    if n.Body != cs_callgraph.BodySource {
+        nodeType = mapBodyKindToStyle(n.Body)
+        tooltip += " [" + n.Body.String() + ", body not analysed]"
+    }
+    return markSink(st, buildNode(
+        st,
         convertNodeID(n.ID, nodeType),
         shortFuncName(n),
-        fullFuncName(n),
+        tooltip,
         nodeType,
-    )
+    ), n)
+}
+
+/* ============================================================================
+ * markSink
+ * ----------------------------------------------------------------------------
+ * Lays the "sink" style over a node built for a sink function, keeping the
+ * shape of its own style, and flags it in the tooltip. Returns dn.
+ * ============================================================================
+ */
+func markSink(st *StyleConfig, dn *DotNode, n *cs_callgraph.Node) *DotNode {
+    if !n.Sink {
+        return dn
+    }
+    if styleMap, ok := st.NodeStyles[string(ns_sink)]; ok {
+        maps.Copy(dn.Attrs, styleMap)
+    }
+    dn.Attrs["tooltip"] += " [sink]"
+    return dn
+}
+
+/* ============================================================================
+ * mapBodyKindToStyle
+ * ----------------------------------------------------------------------------
+ * Maps the body classification of a bodiless function to its node style.
+ * BodyExternal uses "bodiless", since "external" already styles the link
+ * nodes of other packages.
+ * ============================================================================
+ */
+func mapBodyKindToStyle(k cs_callgraph.BodyKind) NodeStyle {
+    switch k {
+    case cs_callgraph.BodyAsm      :   return ns_asm
+    case cs_callgraph.BodyCgo      :   return ns_cgo
+    case cs_callgraph.BodyLinkname :   return ns_linkname
+    case cs_callgraph.BodyExternal :   return ns_bodiless
+    default                        :   return ns_normal
+    }
 }
 
 /* ============================================================================
  * buildNode
  * ----------------------------------------------------------------------------
- * Constructs a DotNode and merges global styling attributes for the given
+ * Constructs a DotNode and merges the styling attributes of st for the given
  * node type. Ensures label and tooltip are always present.
  * ============================================================================
  */
 func buildNode(
+	st *StyleConfig,
 	id string, label string,
 	tooltip string, typ NodeStyle,
 ) *DotNode {
@@ -486,7 +603,7 @@ func buildNode(
 		"tooltip": tooltip,
 	}
 
-	if styleMap, ok := global_styles.NodeStyles[string(typ)]; ok {
+	if styleMap, ok := st.NodeStyles[string(typ)]; ok {
 		maps.Copy(attrs, styleMap)
 	}
 
@@ -512,6 +629,8 @@ func mapEdgeKindToStyle(typ cs_callgraph.EdgeKind) EdgeStyle {
     case cs_callgraph.SendEdge      :   return es_send
     case cs_callgraph.ReceiveEdge   :   return es_receive
     case cs_callgraph.InterfaceEdge :   return es_interface
+    case cs_callgraph.DispatchEdge  :   return es_dispatch
+    case cs_callgraph.ReflectEdge   :   return es_reflect
     default                         :   return es_default
     }
 }
@@ -522,15 +641,36 @@ func mapEdgeKindToStyle(typ cs_callgraph.EdgeKind) EdgeStyle {
  * Converts a callgraph edge into a DotEdge including styling and tooltip.
  * ============================================================================
  */
-func buildEdgeFromCS(e *cs_callgraph.Edge) *DotEdge {
-	return buildEdge(
-		convertNodeID(e.Caller.ID, ns_normal),
-		convertNodeID(e.Callee.ID, ns_normal),
-		mapEdgeKindToStyle(e.Kind),
-		e.Description(),
+func buildEdgeFromCS(st *StyleConfig, e *cs_callgraph.Edge) *DotEdge {
+	return buildEdgeForCS(
+		st,
+		dotNodeID(e.Caller),
+		dotNodeID(e.Callee),
+		e,
 	)
 }
 
+/* ============================================================================
+ * buildEdgeForCS
+ * ----------------------------------------------------------------------------
+ * Builds a DotEdge for a callgraph edge between two already-resolved DOT
+ * node IDs. Edges produced by a whole-program analysis rather than read off
+ * the instruction carry their provenance in the label, e.g. "call (vta)".
+ * ============================================================================
+ */
+func buildEdgeForCS(st *StyleConfig, from string, to string, e *cs_callgraph.Edge) *DotEdge {
+	de := buildEdge(st, from, to, mapEdgeKindToStyle(e.Kind), e.Description())
+	de.Sites = e.Positions()
+	if e.Prov != cs_callgraph.ProvSyntactic {
+		label := e.Kind.String()
+		if styled, ok := de.Attrs["label"]; ok {
+			label = styled
+		}
+		de.Attrs["label"] = fmt.Sprintf("%s (%s)", label, e.Prov)
+	}
+	return de
+}
+
 /* ============================================================================
  * buildEdge
  * ----------------------------------------------------------------------------
@@ -539,6 +679,7 @@ func buildEdgeFromCS(e *cs_callgraph.Edge) *DotEdge {
  * ============================================================================
  */
 func buildEdge(
+	st *StyleConfig,
 	from string, to string,
 	typ EdgeStyle, des string,
 ) *DotEdge {
@@ -547,7 +688,7 @@ func buildEdge(
 		"tooltip": des,
 	}
 
-	if styleMap, ok := global_styles.EdgeStyles[string(typ)]; ok {
+	if styleMap, ok := st.EdgeStyles[string(typ)]; ok {
 		maps.Copy(attrs, styleMap)
 	}
 
@@ -583,22 +724,23 @@ func buildLinkClusterNode(
 	 * Ensure external node exists in cluster
 	 * ------------------------------------------------------- */
 	if _, exists := cluster.Nodes[extNodeID]; !exists {
-		cluster.Nodes[extNodeID] = buildNode(
+		cluster.Nodes[extNodeID] = markSink(pkgGraph.styles, buildNode(
+            pkgGraph.styles,
             extNodeID,
             shortFuncName(e.Callee),
             fullFuncName(e.Callee),
             ns_external,
-        )
+        ), e.Callee)
 	}
 
 	/* -------------------------------------------------------
 	 * Add edge from internal node -> external node
 	 * ------------------------------------------------------- */
-	pkgGraph.Edges = append(pkgGraph.Edges, buildEdge(
-		convertNodeID(n.ID, ns_normal),
+	pkgGraph.Edges = append(pkgGraph.Edges, buildEdgeForCS(
+		pkgGraph.styles,
+		dotNodeID(n),
 		extNodeID,
-		mapEdgeKindToStyle(e.Kind),
-		e.Description(),
+		e,
 	))
 }
 
@@ -611,7 +753,7 @@ func buildLinkClusterNode(
  * If the cluster does not exist, it is created with:
  *   - Label (short package name)
  *   - Tooltip (full package path)
- *   - Global cluster styling applied
+ *   - The graph's cluster styling applied
  * ============================================================================
  */
 func buildCluster(pkgGraph *DotGraph, calleePkg *string) *DotCluster {
@@ -631,9 +773,9 @@ func buildCluster(pkgGraph *DotGraph, calleePkg *string) *DotCluster {
 	}
 
 	/* -------------------------------------------------------
-	 * Apply global cluster styles
+	 * Apply cluster styles
 	 * ------------------------------------------------------- */
-	maps.Copy(attrs, global_styles.Cluster)
+	maps.Copy(attrs, pkgGraph.styles.Cluster)
 
 	/* -------------------------------------------------------
 	 * Create cluster
@@ -0,0 +1,73 @@
+package visualisation
+
+import (
+	"fmt"
+	"maps"
+
+	cs_callgraph "callstat/CS-Callgraph"
+)
+
+// Key of the cycles graph among the package graphs of the HTML report.
+// Parentheses keep it apart from every import path.
+const cyclesView = "(cycles)"
+
+/* ============================================================================
+ * BuildCycleDotGraph
+ * ----------------------------------------------------------------------------
+ * Draws every recursion cycle (non-trivial SCC, see Graph.Cycles) that
+ * touches an in-depth package as a highlighted cluster "cycle <id>", with
+ * the calls between its members. Ids match stats.Cycle.ID. Members of
+ * cycles spanning packages are labelled "pkg.Func"; every member links to
+ * its own package graph, styled from styles. Returns nil if there are no
+ * such cycles.
+ * ============================================================================
+ */
+func BuildCycleDotGraph(g *cs_callgraph.Graph, inDepth func(string) bool, styles *StyleConfig) *DotGraph {
+    dg := newDotGraph(styles)
+
+    for id, scc := range g.Cycles() {
+        members := make(map[*cs_callgraph.Node]bool, len(scc))
+        pkgs    := make(map[string]bool)
+        touches := false
+        for _, n := range scc {
+            members[n] = true
+            pkg := cs_callgraph.NodePackage(n)
+            pkgs[pkg] = true
+            touches = touches || inDepth(pkg)
+        }
+        if !touches {
+            continue
+        }
+
+        attrs := map[string]string{"tooltip": fmt.Sprintf("%d function(s) in %d package(s)", len(scc), len(pkgs))}
+        maps.Copy(attrs, styles.Cluster)
+        maps.Copy(attrs, styles.CycleCluster)
+        cluster := &DotCluster{
+            ID:    fmt.Sprintf("cluster_cycle_%d", id),
+            Label: fmt.Sprintf("cycle %d", id),
+            Attrs: attrs,
+            Nodes: make(map[string]*DotNode),
+        }
+        dg.Clusters[cluster.ID] = cluster
+
+        for _, n := range scc {
+            dn := buildNodeFromCS(styles, n)
+            dn.ID = dotNodeID(n)
+            if len(pkgs) > 1 {
+                dn.Attrs["label"] = shortPkgName(cs_callgraph.NodePackage(n)) + "." + shortFuncName(n)
+            }
+            dn.Attrs["URL"] = "pkg://" + cs_callgraph.NodePackage(n)
+            cluster.Nodes[dn.ID] = dn
+
+            for _, e := range n.Out {
+                if members[e.Callee] && e.Kind != cs_callgraph.InstanceEdge {
+                    dg.Edges = append(dg.Edges, buildEdgeFromCS(styles, e))
+                }
+            }
+        }
+    }
+    if len(dg.Clusters) == 0 {
+        return nil
+    }
+    return dg
+}
@@ -0,0 +1,233 @@
+package visualisation
+
+import (
+	_ "embed"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+
+	diff "callstat/Diff"
+)
+
+/* ============================================================================
+ * diffReportTemplate
+ * ----------------------------------------------------------------------------
+ * Self-contained HTML page for a graph diff. Placeholders:
+ *
+ *   {{SIDES}}    "old → new" labels
+ *   {{SUMMARY}}  count cards
+ *   {{GRAPH}}    inline SVG of the changed subgraph (or why it is missing)
+ *   {{TABLES}}   one table per change list
+ * ============================================================================
+ */
+//go:embed diff_template.html
+var diffReportTemplate string
+
+// Above this many nodes the changed subgraph is not laid out; Graphviz
+// takes minutes and the drawing is unreadable anyway.
+const maxDiffGraphNodes = 500
+
+/* ============================================================================
+ * BuildDiffDotGraph
+ * ----------------------------------------------------------------------------
+ * Draws the changed part of a diff: every added, removed, newly reachable
+ * and newly dead function, plus the unchanged endpoints of added and
+ * removed edges for context. Functions are grouped into one cluster per
+ * package; added nodes / edges are green, removed red and dashed, newly
+ * dead nodes amber, each on top of its base style in styles.
+ * ============================================================================
+ */
+func BuildDiffDotGraph(r *diff.Result, styles *StyleConfig) *DotGraph {
+    g   := newDotGraph(styles)
+    ids := make(map[string]string)
+
+    addNode := func(name, pkg string, typ NodeStyle, note string) string {
+        if id, ok := ids[name]; ok {
+            return id
+        }
+        id := fmt.Sprintf("d%d", len(ids))
+        ids[name] = id
+
+        tooltip := name
+        if note != "" {
+            tooltip += " [" + note + "]"
+        }
+        dn := buildNode(styles, id, diffLabel(name, pkg), tooltip, typ)
+        if pkg == "" {
+            g.Nodes[id] = dn
+        } else {
+            buildCluster(g, &pkg).Nodes[id] = dn
+        }
+        return id
+    }
+
+    for _, n := range r.AddedNodes {
+        addNode(n.Name, n.Package, ns_diff_added, "added")
+    }
+    for _, n := range r.RemovedNodes {
+        addNode(n.Name, n.Package, ns_diff_removed, "removed")
+    }
+    for _, n := range r.NewlyReachable {
+        addNode(n.Name, n.Package, ns_diff_reach, "newly reachable")
+    }
+    for _, n := range r.NewlyDead {
+        addNode(n.Name, n.Package, ns_diff_dead, "newly dead")
+    }
+
+    addEdges := func(edges []*diff.EdgeChange, typ EdgeStyle, note string) {
+        for _, e := range edges {
+            from := addNode(e.Caller, e.CallerPackage, ns_normal, "")
+            to   := addNode(e.Callee, e.CalleePackage, ns_normal, "")
+
+            tooltip := fmt.Sprintf("%s → %s (%s, %s)", e.Caller, e.Callee, e.Kind, note)
+            for _, p := range e.Positions {
+                tooltip += "\n" + p.String()
+            }
+            de := buildEdge(styles, from, to, typ, tooltip)
+            de.Attrs["label"] = e.Kind
+            g.Edges = append(g.Edges, de)
+        }
+    }
+    addEdges(r.AddedEdges, es_diff_added, "added")
+    addEdges(r.RemovedEdges, es_diff_removed, "removed")
+
+    return g
+}
+
+/* -------------------------------------------------------
+ * diffLabel
+ * Function name without its package path, as in the
+ * per-package graphs ("(*T).Run", "main$1").
+ * ------------------------------------------------------- */
+func diffLabel(name, pkg string) string {
+    if pkg == "" {
+        return name
+    }
+    label := strings.Replace(name, pkg+".", "", 1)
+    if label == name {
+        return name
+    }
+    return label
+}
+
+/* ============================================================================
+ * GenerateDiffReport
+ * ----------------------------------------------------------------------------
+ * Writes the HTML view of a diff to htmlOut. The changed subgraph is
+ * written to dotDir/diff.dot and rendered to svgDir/diff.svg with renderer;
+ * if rendering fails or the subgraph is too large the page still lists
+ * every change. styles may be nil for the embedded defaults.
+ * ============================================================================
+ */
+func GenerateDiffReport(r *diff.Result, dotDir, svgDir, htmlOut string, renderer Renderer, styles *StyleConfig) error {
+    styles, err := resolveStyles(styles)
+    if err != nil {
+        return fmt.Errorf("load styles: %w", err)
+    }
+    for _, dir := range []string{dotDir, svgDir} {
+        if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+            return fmt.Errorf("mkdir %s: %w", dir, err)
+        }
+    }
+
+    graphHTML := `<p class="no-graph">No changes.</p>`
+    if !r.Empty() {
+        graphHTML = renderDiffGraph(BuildDiffDotGraph(r, styles), dotDir, svgDir, renderer)
+    }
+
+    out := strings.NewReplacer(
+        "{{SIDES}}",   html.EscapeString(r.Old+" → "+r.New),
+        "{{SUMMARY}}", diffSummaryHTML(r.Summary),
+        "{{GRAPH}}",   graphHTML,
+        "{{TABLES}}",  diffTablesHTML(r),
+    ).Replace(diffReportTemplate)
+
+    return os.WriteFile(htmlOut, []byte(out), 0o644)
+}
+
+func renderDiffGraph(g *DotGraph, dotDir, svgDir string, renderer Renderer) string {
+    nodes := len(g.Nodes)
+    for _, c := range g.Clusters {
+        nodes += len(c.Nodes)
+    }
+    if nodes > maxDiffGraphNodes {
+        return fmt.Sprintf(`<p class="no-graph">%d changed functions - too many to draw; see the tables below.</p>`, nodes)
+    }
+
+    dotPath := filepath.Join(dotDir, "diff.dot")
+    svgPath := filepath.Join(svgDir, "diff.svg")
+    if err := g.WriteDOTToFile(dotPath); err != nil {
+        return fmt.Sprintf(`<p class="no-graph">Could not write %s: %s</p>`, html.EscapeString(dotPath), html.EscapeString(err.Error()))
+    }
+    renderer, err := renderer.resolve()
+    if err == nil {
+        err = renderSVG(g, dotPath, svgPath, renderer)
+    }
+    if err != nil {
+        return fmt.Sprintf(`<p class="no-graph">Rendering failed (%s); the graph is in %s.</p>`, html.EscapeString(err.Error()), html.EscapeString(dotPath))
+    }
+    raw, err := os.ReadFile(svgPath)
+    if err != nil {
+        return fmt.Sprintf(`<p class="no-graph">Could not read %s: %s</p>`, html.EscapeString(svgPath), html.EscapeString(err.Error()))
+    }
+    return `<div id="graph">` + stripSVGPreamble(string(raw)) + `</div>`
+}
+
+func diffSummaryHTML(s diff.Summary) string {
+    card := func(value, label, class string) string {
+        return fmt.Sprintf(`<div class="card"><div class="v %s">%s</div><div class="l">%s</div></div>`, class, value, label)
+    }
+    var b strings.Builder
+    b.WriteString(`<div class="cards">`)
+    b.WriteString(card(fmt.Sprintf("%d → %d", s.OldNodes, s.NewNodes), "Functions", ""))
+    b.WriteString(card(fmt.Sprintf("%d → %d", s.OldEdges, s.NewEdges), "Edges", ""))
+    b.WriteString(card(fmt.Sprintf("+%d", s.AddedNodes), "Added functions", "added"))
+    b.WriteString(card(fmt.Sprintf("-%d", s.RemovedNodes), "Removed functions", "removed"))
+    b.WriteString(card(fmt.Sprintf("+%d", s.AddedEdges), "Added edges", "added"))
+    b.WriteString(card(fmt.Sprintf("-%d", s.RemovedEdges), "Removed edges", "removed"))
+    b.WriteString(card(fmt.Sprint(s.NewlyReachable), "Newly reachable", "added"))
+    b.WriteString(card(fmt.Sprint(s.NewlyDead), "Newly dead", "dead"))
+    b.WriteString(`</div>`)
+    return b.String()
+}
+
+func diffTablesHTML(r *diff.Result) string {
+    var b strings.Builder
+
+    nodeTable := func(title, class string, nodes []*diff.NodeChange) {
+        if len(nodes) == 0 {
+            return
+        }
+        fmt.Fprintf(&b, `<h2 class="%s">%s (%d)</h2><table><tr><th>Function</th><th>Kind</th><th>Package</th></tr>`, class, title, len(nodes))
+        for _, n := range nodes {
+            fmt.Fprintf(&b, `<tr><td>%s</td><td class="kind">%s</td><td>%s</td></tr>`,
+                html.EscapeString(n.Name), html.EscapeString(n.Kind), html.EscapeString(n.Package))
+        }
+        b.WriteString(`</table>`)
+    }
+    edgeTable := func(title, class string, edges []*diff.EdgeChange) {
+        if len(edges) == 0 {
+            return
+        }
+        fmt.Fprintf(&b, `<h2 class="%s">%s (%d)</h2><table><tr><th>Caller</th><th>Kind</th><th>Callee</th><th>Sites</th></tr>`, class, title, len(edges))
+        for _, e := range edges {
+            sites := make([]string, len(e.Positions))
+            for i, p := range e.Positions {
+                sites[i] = html.EscapeString(fmt.Sprintf("%s:%d", filepath.Base(p.File), p.Line))
+            }
+            fmt.Fprintf(&b, `<tr><td>%s</td><td class="kind">%s</td><td>%s</td><td>%s</td></tr>`,
+                html.EscapeString(e.Caller), html.EscapeString(e.Kind), html.EscapeString(e.Callee), strings.Join(sites, "<br>"))
+        }
+        b.WriteString(`</table>`)
+    }
+
+    nodeTable("Added functions", "added", r.AddedNodes)
+    nodeTable("Removed functions", "removed", r.RemovedNodes)
+    nodeTable("Newly reachable", "added", r.NewlyReachable)
+    nodeTable("Newly dead", "dead", r.NewlyDead)
+    edgeTable("Added edges", "added", r.AddedEdges)
+    edgeTable("Removed edges", "removed", r.RemovedEdges)
+    return b.String()
+}
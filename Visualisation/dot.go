@@ -1,5 +1,7 @@
 package visualisation
 
+import "go/token"
+
 /* ============================================================================
  * Dot data strucutres
  * ----------------------------------------------------------------------------
@@ -11,6 +13,8 @@ type DotGraph struct {
 	Nodes    map[string]*   DotNode
 	Edges    []*            DotEdge
 	Clusters map[string]*   DotCluster
+
+	styles   *StyleConfig // Style set the graph is drawn with (not written)
 }
 
 type DotNode struct {
@@ -23,6 +27,7 @@ type DotEdge struct {
 	From  string
 	To    string
 	Attrs map[string]string
+	Sites []token.Position // Source positions of the call sites (not written)
 }
 
 type DotCluster struct {
@@ -34,15 +39,16 @@ type DotCluster struct {
 
 
 /* ============================================================================
- * 
+ * newDotGraph
  * ----------------------------------------------------------------------------
- * 
+ * Creates an empty graph whose nodes, edges and clusters are styled from st.
  * ============================================================================
  */
-func newDotGraph() *DotGraph {
+func newDotGraph(st *StyleConfig) *DotGraph {
 	return &DotGraph{
 		Nodes:    make(map[string]*DotNode),
 		Edges:    make([]*DotEdge, 0),
 		Clusters: make(map[string]*DotCluster),
+		styles:   st,
 	}
 }
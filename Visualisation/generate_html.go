@@ -20,14 +20,17 @@ import (
  * ----------------------------------------------------------------------------
  * Self-contained HTML template.  Two placeholders are substituted at runtime:
  *
- *   {{SVG_DATA_JSON}}  JSON object mapping package path → bare SVG string
- *   {{PACKAGE_LIST}}   HTML <div> elements for the sidebar
+ *   {{SVG_DATA_JSON}}   JSON object mapping package path → bare SVG string
+ *   {{SITE_DATA_JSON}}  JSON object mapping package path → call-site table
+ *                       (see linkEdgeSites)
+ *   {{PACKAGE_LIST}}    HTML <div> elements for the sidebar
  *
  * Navigation works like a browser:
  *   - Sidebar click           → switchPackage (clears fwd stack)
  *   - Click ext-package node  → switchPackage (same)
  *   - ← Back / Alt+←         → goBack
  *   - Fwd → / Alt+→           → goFwd
+ *   - Click edge              → list its call sites with source
  *   - Scroll                  → zoom toward cursor
  *   - Drag                    → pan
  *   - ⌂ button               → reset view
@@ -77,18 +80,22 @@ func escapeJSTemplateLiteral(s string) string {
 /* ============================================================================
  * pkgGroup
  * ----------------------------------------------------------------------------
- * Classifies a package path into one of three sidebar groups:
+ * Classifies a package path into one of the sidebar groups:
  *
+ *   "views"     - graphs that are not a package (the cycles view)
  *   "internal"  - belongs to the project (path has projectRoot as prefix)
- *   "stdlib"    - Go standard library (first path segment contains no dot)
+ *   "stdlib"    - Go standard library (listed in std)
  *   "external"  - third-party module (everything else)
  * ============================================================================
  */
-func pkgGroup(path, projectRoot string) string {
+func pkgGroup(path, projectRoot string, std cs_callgraph.STDLib) string {
+	if path == cyclesView {
+		return "views"
+	}
 	if projectRoot != "" && strings.HasPrefix(path, projectRoot) {
 		return "internal"
 	}
-	if cs_callgraph.IsStdlib(path) {
+	if std.Contains(path) {
         return "stdlib"
     }
 	return "external"
@@ -96,17 +103,18 @@ func pkgGroup(path, projectRoot string) string {
 /* ============================================================================
  * buildSidebarHTML
  * ----------------------------------------------------------------------------
- * Renders the sidebar package list as three collapsible <details> groups.
+ * Renders the sidebar package list as collapsible <details> groups.
  * Groups that are empty are omitted entirely.
  * ============================================================================
  */
-func buildSidebarHTML(pkgs []string, svgMap map[string]string, projectRoot string) string {
+func buildSidebarHTML(pkgs []string, svgMap map[string]string, projectRoot string, std cs_callgraph.STDLib) string {
 	type group struct {
 		key    string
 		label  string
 		open   bool
 	}
 	groups := []group{
+		{"views"    , "Views"           , true},
 		{"internal" , "Project"         , true},
 		{"stdlib"   , "Standard Library", false},
 		{"external" , "External"        , false},
@@ -118,7 +126,7 @@ func buildSidebarHTML(pkgs []string, svgMap map[string]string, projectRoot strin
 		if _, ok := svgMap[pkg]; !ok {
 			continue
 		}
-		buckets[pkgGroup(pkg, projectRoot)] = append(buckets[pkgGroup(pkg, projectRoot)], pkg)
+		buckets[pkgGroup(pkg, projectRoot, std)] = append(buckets[pkgGroup(pkg, projectRoot, std)], pkg)
 	}
  
 	var sb strings.Builder
@@ -152,7 +160,7 @@ func buildSidebarHTML(pkgs []string, svgMap map[string]string, projectRoot strin
  * GenerateHTMLReport
  * ----------------------------------------------------------------------------
  * Builds DOT + SVG artefacts per package, then writes a single self-contained
- * HTML file with:
+ * HTML file (or, with split, an index page and per-package assets) with:
  *   - A filterable package sidebar
  *   - A pan/zoomable SVG canvas
  *   - Back / Forward navigation
@@ -165,9 +173,17 @@ func buildSidebarHTML(pkgs []string, svgMap map[string]string, projectRoot strin
  *   dotDir         - directory for intermediate DOT files
  *   svgDir         - directory for intermediate SVG files
  *   htmlOut        - path of the HTML file to write
- *   concurrency    - passed through; unused here (sequential is fine for I/O)
+ *   concurrency    - number of packages rendered in parallel (<= 0 = one per
+ *                   CPU); output does not depend on it
  *   projectRoot    - module path prefix used to identify internal packages
  *                   (e.g. "github.com/you/yourrepo"); pass "" to skip grouping
+ *   sizing         - optional node sizing by a metric (nil = uniform nodes)
+ *   renderer       - how the SVGs are produced (see Renderer)
+ *   split          - write an index page plus one asset per package under
+ *                   <report>_files/, loaded when the package is selected,
+ *                   instead of one self-contained file (for large projects)
+ *   styles         - style configuration (see LoadStyles); nil uses the
+ *                   embedded defaults
 
  * ============================================================================
  */
@@ -182,22 +198,36 @@ func GenerateHTMLReport(
     maxDepth    int,
 	statsJSONPath string,
 	projectRoot   string,
+	sizing        *NodeSizing,
+	renderer      Renderer,
+	split         bool,
+	styles        *StyleConfig,
 
 ) error {
 
     /* -------------------------------------------------------
      * 1. STYLES
      * ------------------------------------------------------- */
-    if global_styles == nil {
-        if err := LoadInternalStyles(); err != nil {
-            return fmt.Errorf("load styles: %w", err)
-        }
+    styles, err := resolveStyles(styles)
+    if err != nil {
+        return fmt.Errorf("load styles: %w", err)
     }
 
     /* -------------------------------------------------------
      * 2. BUILD GRAPHS
      * ------------------------------------------------------- */
-    graphs := BuildDotGraphPerPackage(cg, skipPkg)
+    graphs := BuildDotGraphPerPackage(cg, skipPkg, styles)
+    inDepth := func(pkg string) bool {
+        if _, skip := skipPkg[pkg]; skip {
+            return false
+        }
+        d, ok := depthMap[pkg]
+        return maxDepth == -1 || (ok && d <= maxDepth)
+    }
+    if cycles := BuildCycleDotGraph(cg, inDepth, styles); cycles != nil {
+        graphs[cyclesView] = cycles
+    }
+    sizeNodes(graphs, sizing)
 
     /* -------------------------------------------------------
      * 3. ENSURE OUTPUT DIRECTORIES
@@ -220,52 +250,64 @@ func GenerateHTMLReport(
     /* -------------------------------------------------------
      * 5. GENERATE DOT + SVG FILES
      * ------------------------------------------------------- */
-    fmt.Printf("%-60s | %-12s | %-12s\n", "Package", "DOT Gen", "SVG Gen")
-    fmt.Println(strings.Repeat("-", 90))
-
+    var todo []string
     for _, pkg := range pkgs {
         if _, skip := skipPkg[pkg]; skip {
             continue
         }
-        if maxDepth != -1 {
+        if maxDepth != -1 && pkg != cyclesView {
             if d, ok := depthMap[pkg]; !ok || d > maxDepth {
                 continue
             }
         }
+        todo = append(todo, pkg)
+    }
 
-        san     := sanitizePkg(pkg)
-        dotPath := filepath.Join(dotDir, san+".dot")
-        svgPath := filepath.Join(svgDir, san+".svg")
+    renderer, err = renderer.resolve()
+    if err != nil {
+        return err
+    }
+    fmt.Printf("[info] rendering SVGs with %s\n", renderer)
 
-        // Timer for DOT generation (Writing the file)
-        tDotStart := time.Now()
-        if err := graphs[pkg].WriteDOTToFile(dotPath); err != nil {
-            log.Printf("[WARN] dot write %s: %v", pkg, err)
-            continue
-        }
-        dotElapsed := time.Since(tDotStart)
+    tRender := time.Now()
+    results := renderPackages(graphs, todo, dotDir, svgDir, newSourceCache(), renderer, concurrency)
+    elapsed := time.Since(tRender)
 
-        // Timer for SVG generation (Calling the external 'dot' command)
-        tSvgStart := time.Now()
-        if err := generateSVG(dotPath, svgPath); err != nil {
-            log.Printf("[WARN] svg gen  %s: %v", pkg, err)
-        }
-        svgElapsed := time.Since(tSvgStart)
+    // Print the timing table in package order once every worker is done
+    siteMap := make(map[string][][]siteSnippet, len(results))
+    var dotTotal, svgTotal time.Duration
 
-        // Print results for this package
-        fmt.Printf("%-60s | %-12v | %-12v\n", 
-            shortPkgName(pkg), 
-            dotElapsed.Round(time.Millisecond), 
-            svgElapsed.Round(time.Millisecond),
+    fmt.Printf("%-60s | %-12s | %-12s\n", "Package", "DOT Gen", "SVG Gen")
+    fmt.Println(strings.Repeat("-", 90))
+    for _, r := range results {
+        siteMap[r.Pkg] = r.Sites
+        dotTotal      += r.Dot
+        svgTotal      += r.SVG
+        fmt.Printf("%-60s | %-12v | %-12v\n",
+            shortPkgName(r.Pkg),
+            r.Dot.Round(time.Millisecond),
+            r.SVG.Round(time.Millisecond),
         )
     }
+    fmt.Println(strings.Repeat("-", 90))
+    fmt.Printf("%-60s | %-12v | %-12v\n",
+        fmt.Sprintf("Total (%d packages, wall %v)", len(results), elapsed.Round(time.Millisecond)),
+        dotTotal.Round(time.Millisecond),
+        svgTotal.Round(time.Millisecond),
+    )
+
+    for _, r := range results {
+        if r.Err != nil {
+            log.Printf("[WARN] %s: %v", r.Pkg, r.Err)
+        }
+    }
 
     /* -------------------------------------------------------
      * 6. READ SVGs INTO MEMORY
      * ------------------------------------------------------- */
     svgMap := make(map[string]string, len(pkgs))
     for _, pkg := range pkgs {
-        if maxDepth != -1 {
+        if maxDepth != -1 && pkg != cyclesView {
             if d, ok := depthMap[pkg]; !ok || d > maxDepth {
                 continue
             }
@@ -282,14 +324,41 @@ func GenerateHTMLReport(
 
     /* -------------------------------------------------------
      * 7. MARSHAL SVG MAP → JSON
+     * A split report embeds only the package names and the
+     * asset that supplies each one.
      * ------------------------------------------------------- */
-    svgBytes, err := json.Marshal(svgMap)
+    var svgData, siteData any = svgMap, siteMap
+    assets := map[string]string{}
+    if split {
+        if assets, err = writeReportAssets(htmlOut, svgMap, siteMap); err != nil {
+            return err
+        }
+        stubs := make(map[string]*string, len(svgMap))
+        for pkg := range svgMap {
+            stubs[pkg] = nil
+        }
+        svgData, siteData = stubs, struct{}{}
+    }
+
+    svgBytes, err := json.Marshal(svgData)
     if err != nil {
         return fmt.Errorf("marshal svg map: %w", err)
     }
 
 	svgJSONStr := escapeJSTemplateLiteral(string(svgBytes))
 
+    siteBytes, err := json.Marshal(siteData)
+    if err != nil {
+        return fmt.Errorf("marshal site map: %w", err)
+    }
+    siteJSONStr := escapeJSTemplateLiteral(string(siteBytes))
+
+    assetBytes, err := json.Marshal(assets)
+    if err != nil {
+        return fmt.Errorf("marshal asset map: %w", err)
+    }
+    assetJSONStr := escapeJSTemplateLiteral(string(assetBytes))
+
 
     /* -------------------------------------------------------
 	 * 8. READ STATS JSON
@@ -314,7 +383,7 @@ func GenerateHTMLReport(
 	/* -------------------------------------------------------
 	 * 9. BUILD SIDEBAR ITEMS
 	 * ------------------------------------------------------- */
-	sidebarHTML := buildSidebarHTML(pkgs, svgMap, projectRoot)
+	sidebarHTML := buildSidebarHTML(pkgs, svgMap, projectRoot, cg.Std)
 
 
     /* -------------------------------------------------------
@@ -323,6 +392,8 @@ func GenerateHTMLReport(
     out := strings.NewReplacer(
         "{{SVG_DATA_JSON}}", svgJSONStr, // Use our escaped string here
         "{{STATS_DATA_JSON}}", statsJSONStr,
+        "{{SITE_DATA_JSON}}",  siteJSONStr,
+        "{{ASSET_FILES_JSON}}", assetJSONStr,
 		"{{PACKAGE_LIST}}",   sidebarHTML,
     ).Replace(htmlReportTemplate)
 
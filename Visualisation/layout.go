@@ -0,0 +1,512 @@
+package visualisation
+
+import (
+    "math"
+    "sort"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+)
+
+// Spacing of the built-in layout, in SVG user units (points)
+const (
+    layoutMargin     = 8.0
+    layoutRankGap    = 90.0 // between rank columns; room for edge labels
+    layoutNodeGap    = 18.0 // between nodes stacked in one column
+    layoutBandGap    = 24.0 // between cluster bands
+    layoutClusterPad = 12.0
+    layoutSweeps     = 8
+)
+
+/* ============================================================================
+ * graphLayout
+ * ----------------------------------------------------------------------------
+ * Positions computed by layoutGraph for one DotGraph.
+ *
+ *   nodes     every node, clusters' nodes first (sorted by cluster, then ID)
+ *   clusters  clusters sorted by ID, with their boxes
+ *   edges     edges whose endpoints both exist, in DotGraph order
+ *   w, h      size of the drawing including margins
+ * ============================================================================
+ */
+type graphLayout struct {
+    nodes    []*layoutNode
+    clusters []*layoutCluster
+    edges    []*layoutEdge
+    w, h     float64
+}
+
+type layoutNode struct {
+    dn      *DotNode
+    cluster int // index into graphLayout.clusters, -1 = top level
+    rank    int
+    key     float64 // ordering key within its band and rank
+    x, y    float64 // top-left corner
+    w, h    float64
+}
+
+type layoutCluster struct {
+    dc             *DotCluster
+    x0, y0, x1, y1 float64
+}
+
+type layoutEdge struct {
+    de       *DotEdge
+    from, to *layoutNode
+    back     bool          // reversed to break a cycle while ranking
+    curve    [4][2]float64 // cubic Bézier control points, set by route
+}
+
+func (n *layoutNode) cx() float64 { return n.x + n.w/2 }
+func (n *layoutNode) cy() float64 { return n.y + n.h/2 }
+
+/* ============================================================================
+ * layoutGraph
+ * ----------------------------------------------------------------------------
+ * A small layered layout in the spirit of `dot` with rankdir=LR:
+ *
+ *   1. Break cycles by reversing DFS back edges.
+ *   2. Rank nodes by longest path from the sources; a source is then pulled
+ *      right next to its nearest callee.
+ *   3. Give every cluster (and the top-level nodes) a horizontal band, and
+ *      order bands and the nodes within each band and rank by barycentre
+ *      sweeps over their neighbours.
+ *   4. Ranks become columns, bands are stacked top to bottom, so cluster
+ *      boxes never overlap.
+ *   5. Route every edge as a curve from the caller's right side into the
+ *      callee's left side; edges that run backwards or within one column
+ *      loop out to the right of both nodes instead.
+ *
+ * The result is deterministic for a given graph.
+ * ============================================================================
+ */
+func layoutGraph(g *DotGraph) *graphLayout {
+    l    := &graphLayout{}
+    byID := make(map[string]*layoutNode)
+
+    /* -------------------------------------------------------
+     * 1. NODES, CLUSTERS, EDGES
+     * ------------------------------------------------------- */
+    clusterIDs := make([]string, 0, len(g.Clusters))
+    for id := range g.Clusters {
+        clusterIDs = append(clusterIDs, id)
+    }
+    sort.Strings(clusterIDs)
+
+    add := func(dn *DotNode, cluster int) {
+        if _, dup := byID[dn.ID]; dup {
+            return
+        }
+        n := &layoutNode{dn: dn, cluster: cluster}
+        n.w, n.h = nodeSize(dn)
+        byID[dn.ID] = n
+        l.nodes = append(l.nodes, n)
+    }
+    for i, id := range clusterIDs {
+        c := g.Clusters[id]
+        l.clusters = append(l.clusters, &layoutCluster{dc: c})
+        for _, nid := range sortedNodeIDs(c.Nodes) {
+            add(c.Nodes[nid], i)
+        }
+    }
+    for _, nid := range sortedNodeIDs(g.Nodes) {
+        add(g.Nodes[nid], -1)
+    }
+
+    out := make(map[*layoutNode][]*layoutEdge)
+    for _, de := range g.Edges {
+        from, to := byID[de.From], byID[de.To]
+        if from == nil || to == nil {
+            continue
+        }
+        e := &layoutEdge{de: de, from: from, to: to}
+        l.edges = append(l.edges, e)
+        out[from] = append(out[from], e)
+    }
+
+    /* -------------------------------------------------------
+     * 2. RANKS
+     * ------------------------------------------------------- */
+    breakCycles(l.nodes, out)
+    rankNodes(l.nodes, l.edges)
+
+    /* -------------------------------------------------------
+     * 3. ORDER
+     * ------------------------------------------------------- */
+    neighbours := make(map[*layoutNode][]*layoutNode)
+    for _, e := range l.edges {
+        if e.from != e.to {
+            neighbours[e.from] = append(neighbours[e.from], e.to)
+            neighbours[e.to]   = append(neighbours[e.to], e.from)
+        }
+    }
+    for i, n := range l.nodes {
+        n.key = float64(i)
+    }
+    for i := 0; i < layoutSweeps; i++ {
+        rows := l.assignRows()
+        for _, n := range l.nodes {
+            if nb := neighbours[n]; len(nb) > 0 {
+                sum := 0.0
+                for _, m := range nb {
+                    sum += rows[m]
+                }
+                n.key = sum / float64(len(nb))
+            } else {
+                n.key = rows[n]
+            }
+        }
+    }
+
+    /* -------------------------------------------------------
+     * 4. COORDINATES
+     * ------------------------------------------------------- */
+    l.place()
+
+    /* -------------------------------------------------------
+     * 5. EDGES
+     * ------------------------------------------------------- */
+    for _, e := range l.edges {
+        e.route()
+        for _, p := range e.curve {
+            l.w = max(l.w, p[0]+layoutMargin)
+        }
+    }
+    return l
+}
+
+func sortedNodeIDs(nodes map[string]*DotNode) []string {
+    ids := make([]string, 0, len(nodes))
+    for id := range nodes {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+    return ids
+}
+
+/* -------------------------------------------------------
+ * breakCycles
+ * Marks the DFS back edges (and self loops) as back, so
+ * the remaining edges form a DAG.
+ * ------------------------------------------------------- */
+func breakCycles(nodes []*layoutNode, out map[*layoutNode][]*layoutEdge) {
+    const (
+        unvisited = iota
+        onStack
+        done
+    )
+    state := make(map[*layoutNode]int, len(nodes))
+
+    var visit func(n *layoutNode)
+    visit = func(n *layoutNode) {
+        state[n] = onStack
+        for _, e := range out[n] {
+            switch state[e.to] {
+            case onStack:
+                e.back = true
+            case unvisited:
+                visit(e.to)
+            }
+        }
+        state[n] = done
+    }
+    for _, n := range nodes {
+        if state[n] == unvisited {
+            visit(n)
+        }
+    }
+}
+
+/* -------------------------------------------------------
+ * rankNodes
+ * Longest path over the forward edges, then sources move
+ * up to one rank before their nearest successor.
+ * ------------------------------------------------------- */
+func rankNodes(nodes []*layoutNode, edges []*layoutEdge) {
+    succ     := make(map[*layoutNode][]*layoutNode)
+    indegree := make(map[*layoutNode]int)
+    for _, e := range edges {
+        if !e.back {
+            succ[e.from]  = append(succ[e.from], e.to)
+            indegree[e.to]++
+        }
+    }
+
+    var order []*layoutNode
+    queue := []*layoutNode{}
+    for _, n := range nodes {
+        if indegree[n] == 0 {
+            queue = append(queue, n)
+        }
+    }
+    remaining := make(map[*layoutNode]int, len(indegree))
+    for n, d := range indegree {
+        remaining[n] = d
+    }
+    for len(queue) > 0 {
+        n := queue[0]
+        queue = queue[1:]
+        order = append(order, n)
+        for _, m := range succ[n] {
+            m.rank = max(m.rank, n.rank+1)
+            if remaining[m]--; remaining[m] == 0 {
+                queue = append(queue, m)
+            }
+        }
+    }
+
+    for i := len(order) - 1; i >= 0; i-- {
+        n := order[i]
+        if indegree[n] != 0 || len(succ[n]) == 0 {
+            continue
+        }
+        nearest := math.MaxInt
+        for _, m := range succ[n] {
+            nearest = min(nearest, m.rank)
+        }
+        n.rank = nearest - 1
+    }
+}
+
+/* -------------------------------------------------------
+ * assignRows
+ * Sorts bands and nodes by their current keys and returns
+ * each node's row: band offset plus its index within its
+ * band and rank.
+ * ------------------------------------------------------- */
+func (l *graphLayout) assignRows() map[*layoutNode]float64 {
+    bands := l.bands()
+    rows  := make(map[*layoutNode]float64, len(l.nodes))
+    offset := 0.0
+    for _, band := range bands {
+        height := 0
+        for _, col := range band {
+            for i, n := range col {
+                rows[n] = offset + float64(i)
+            }
+            height = max(height, len(col))
+        }
+        offset += float64(height) + 1
+    }
+    return rows
+}
+
+/* -------------------------------------------------------
+ * bands
+ * Groups nodes into bands (clusters, then the top-level
+ * nodes) ordered by mean key, each split into columns by
+ * rank and sorted by key. Ties fall back to the node ID.
+ * ------------------------------------------------------- */
+func (l *graphLayout) bands() [][][]*layoutNode {
+    members := make(map[int][]*layoutNode)
+    for _, n := range l.nodes {
+        members[n.cluster] = append(members[n.cluster], n)
+    }
+
+    ids := make([]int, 0, len(members))
+    key := make(map[int]float64, len(members))
+    for id, ns := range members {
+        sum := 0.0
+        for _, n := range ns {
+            sum += n.key
+        }
+        ids     = append(ids, id)
+        key[id] = sum / float64(len(ns))
+    }
+    sort.Slice(ids, func(i, j int) bool {
+        if key[ids[i]] != key[ids[j]] {
+            return key[ids[i]] < key[ids[j]]
+        }
+        return ids[i] < ids[j]
+    })
+
+    bands := make([][][]*layoutNode, 0, len(ids))
+    for _, id := range ids {
+        ns := members[id]
+        sort.SliceStable(ns, func(i, j int) bool {
+            if ns[i].key != ns[j].key {
+                return ns[i].key < ns[j].key
+            }
+            return ns[i].dn.ID < ns[j].dn.ID
+        })
+        var cols [][]*layoutNode
+        for _, n := range ns {
+            for len(cols) <= n.rank {
+                cols = append(cols, nil)
+            }
+            cols[n.rank] = append(cols[n.rank], n)
+        }
+        bands = append(bands, cols)
+    }
+    return bands
+}
+
+/* -------------------------------------------------------
+ * place
+ * Turns ranks into columns and bands into stacked rows,
+ * and fits the cluster boxes around their nodes.
+ * ------------------------------------------------------- */
+func (l *graphLayout) place() {
+    // Rank 0 may be empty once sources have been pulled right
+    minRank, maxRank := 0, 0
+    for i, n := range l.nodes {
+        if i == 0 || n.rank < minRank {
+            minRank = n.rank
+        }
+        maxRank = max(maxRank, n.rank)
+    }
+    for _, n := range l.nodes {
+        n.rank -= minRank
+    }
+    maxRank -= minRank
+
+    colW := make([]float64, maxRank+1)
+    for _, n := range l.nodes {
+        colW[n.rank] = max(colW[n.rank], n.w)
+    }
+    colX := make([]float64, maxRank+1)
+    x := layoutMargin + layoutClusterPad
+    for r := range colW {
+        colX[r] = x
+        x      += colW[r] + layoutRankGap
+    }
+    l.w = x - layoutRankGap + layoutClusterPad + layoutMargin
+
+    y := layoutMargin
+    for _, band := range l.bands() {
+        var c *layoutCluster
+        top := y
+        if n := firstNode(band); n != nil && n.cluster >= 0 {
+            c  = l.clusters[n.cluster]
+            y += clusterHeaderHeight(c.dc)
+        }
+
+        height := 0.0
+        for r, col := range band {
+            cy := y
+            for _, n := range col {
+                n.x = colX[r] + (colW[r]-n.w)/2
+                n.y = cy
+                cy += n.h + layoutNodeGap
+            }
+            height = max(height, cy-layoutNodeGap-y)
+        }
+        y += height
+
+        if c != nil {
+            c.x0, c.y0 = math.Inf(1), top
+            c.x1, c.y1 = math.Inf(-1), y+layoutClusterPad
+            for _, col := range band {
+                for _, n := range col {
+                    c.x0 = min(c.x0, n.x-layoutClusterPad)
+                    c.x1 = max(c.x1, n.x+n.w+layoutClusterPad)
+                }
+            }
+            c.x1 = max(c.x1, c.x0+textWidth(clusterLabel(c.dc), 14)+2*layoutClusterPad)
+            l.w  = max(l.w, c.x1+layoutMargin)
+            y    = c.y1
+        }
+        y += layoutBandGap
+    }
+    l.h = max(y-layoutBandGap, layoutMargin) + layoutMargin
+}
+
+/* -------------------------------------------------------
+ * route
+ * Sets the control points of e. A curve stays inside the
+ * hull of its control points, which bounds the drawing.
+ * ------------------------------------------------------- */
+func (e *layoutEdge) route() {
+    from, to := e.from, e.to
+    switch {
+    case from == to:
+        x, y, dy := from.x+from.w, from.cy(), from.h/2+8
+        e.curve = [4][2]float64{{x, y - 6}, {x + 40, y - dy}, {x + 40, y + dy}, {x, y + 6}}
+    case to.x > from.x+from.w:
+        sx, sy := from.x+from.w, from.cy()
+        ex, ey := to.x, to.cy()
+        dx     := (ex - sx) / 2
+        e.curve = [4][2]float64{{sx, sy}, {sx + dx, sy}, {ex - dx, ey}, {ex, ey}}
+    default:
+        sx, sy := from.x+from.w, from.cy()
+        ex, ey := to.x+to.w, to.cy()
+        out    := max(sx, ex) + 30 + math.Abs(ey-sy)*0.15
+        e.curve = [4][2]float64{{sx, sy}, {out, sy}, {out, ey}, {ex, ey}}
+    }
+}
+
+func firstNode(band [][]*layoutNode) *layoutNode {
+    for _, col := range band {
+        if len(col) > 0 {
+            return col[0]
+        }
+    }
+    return nil
+}
+
+/* ============================================================================
+ * Sizes
+ * ----------------------------------------------------------------------------
+ * Text is measured with a fixed average glyph width, which is close enough
+ * for the sans-serif labels the SVG writer uses. Graphviz width and height
+ * attributes (inches, as set by sizeNodes) are honoured as minimums.
+ * ============================================================================
+ */
+func nodeLabel(dn *DotNode) string {
+    if l, ok := dn.Attrs["label"]; ok {
+        return l
+    }
+    if dn.Label != "" {
+        return dn.Label
+    }
+    return dn.ID
+}
+
+func clusterLabel(c *DotCluster) string {
+    if c.Label != "" {
+        return c.Label
+    }
+    return c.Attrs["label"]
+}
+
+// labelLines splits a DOT label at its \n, \l and \r escapes
+func labelLines(label string) []string {
+    r := strings.NewReplacer(`\l`, "\n", `\r`, "\n", `\n`, "\n")
+    return strings.Split(strings.TrimRight(r.Replace(label), "\n"), "\n")
+}
+
+func fontSize(attrs map[string]string) float64 {
+    if v, err := strconv.ParseFloat(attrs["fontsize"], 64); err == nil && v > 0 {
+        return v
+    }
+    return 14
+}
+
+func textWidth(s string, size float64) float64 {
+    widest := 0
+    for _, line := range labelLines(s) {
+        widest = max(widest, utf8.RuneCountInString(line))
+    }
+    return float64(widest) * size * 0.6
+}
+
+func nodeSize(dn *DotNode) (w, h float64) {
+    size  := fontSize(dn.Attrs)
+    label := nodeLabel(dn)
+    w = textWidth(label, size) + 20
+    h = float64(len(labelLines(label)))*size*1.2 + 16
+    if v, err := strconv.ParseFloat(dn.Attrs["width"], 64); err == nil {
+        w = max(w, v*72)
+    }
+    if v, err := strconv.ParseFloat(dn.Attrs["height"], 64); err == nil {
+        h = max(h, v*72)
+    }
+    return w, h
+}
+
+func clusterHeaderHeight(c *DotCluster) float64 {
+    if clusterLabel(c) == "" {
+        return layoutClusterPad
+    }
+    return layoutClusterPad + 14*1.2 + 6
+}
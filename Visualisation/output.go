@@ -1,6 +1,7 @@
 package visualisation
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
@@ -23,6 +24,10 @@ import (
  *   - dotDir: folder for DOT files
  *   - svgDir: folder for SVG files
  *   - concurrency: number of goroutines (0 = sequential)
+ *   - styles: style configuration (nil = embedded defaults)
+ *
+ * Per-package write failures are logged and skipped; setup failures (styles,
+ * output folders) are returned.
  * ============================================================================
  */
 func GenerateDOTAndSVG(
@@ -33,20 +38,20 @@ func GenerateDOTAndSVG(
     skipPkg     map[string]struct{},
     depthMap    map[string]int,
     maxDepth    int,
-) {
-    if global_styles == nil {
-        if err := LoadInternalStyles(); err != nil {
-            log.Fatalf("failed to load internal styles: %v", err)
-        }
+    styles      *StyleConfig,
+) error {
+    styles, err := resolveStyles(styles)
+    if err != nil {
+        return fmt.Errorf("failed to load internal styles: %w", err)
     }
 
-    graphs := BuildDotGraphPerPackage(cg, skipPkg)
+    graphs := BuildDotGraphPerPackage(cg, skipPkg, styles)
 
     if err := os.MkdirAll(dotDir, os.ModePerm); err != nil {
-        log.Fatalf("failed to create dot folder: %v", err)
+        return fmt.Errorf("failed to create dot folder: %w", err)
     }
     if err := os.MkdirAll(svgDir, os.ModePerm); err != nil {
-        log.Fatalf("failed to create svg folder: %v", err)
+        return fmt.Errorf("failed to create svg folder: %w", err)
     }
 
     // Returns true if this package should get its own DOT/SVG file
@@ -100,6 +105,7 @@ func GenerateDOTAndSVG(
         }
         wg.Wait()
     }
+    return nil
 }
 /* ============================================================================
  * generateSVG
@@ -0,0 +1,107 @@
+package visualisation
+
+import (
+    "fmt"
+    "path/filepath"
+    "runtime"
+    "sync"
+    "time"
+)
+
+/* ============================================================================
+ * renderResult
+ * ----------------------------------------------------------------------------
+ * Outcome of rendering one package.
+ *
+ *   Pkg    package path (or cyclesView)
+ *   Sites  call-site table of the package's edges (see linkEdgeSites)
+ *   Dot    time spent linking sites and writing the DOT file
+ *   SVG    time spent rendering the SVG (0 if it did not run)
+ *   Err    first failure; a failed DOT write skips the SVG step
+ * ============================================================================
+ */
+type renderResult struct {
+    Pkg   string
+    Sites [][]siteSnippet
+    Dot   time.Duration
+    SVG   time.Duration
+    Err   error
+}
+
+/* ============================================================================
+ * renderPackages
+ * ----------------------------------------------------------------------------
+ * Writes the DOT file of every package in pkgs and renders it to SVG with
+ * renderer (already resolved), using up to workers goroutines (<= 0 = one
+ * per CPU). Results come back in the order of pkgs, whatever order the
+ * workers finish in.
+ * ============================================================================
+ */
+func renderPackages(
+    graphs   map[string]*DotGraph,
+    pkgs     []string,
+    dotDir   string,
+    svgDir   string,
+    sources  *sourceCache,
+    renderer Renderer,
+    workers  int,
+) []*renderResult {
+    if workers <= 0 {
+        workers = runtime.NumCPU()
+    }
+    workers = max(1, min(workers, len(pkgs)))
+
+    results := make([]*renderResult, len(pkgs))
+    jobs    := make(chan int)
+    var wg sync.WaitGroup
+
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range jobs {
+                results[i] = renderPackage(graphs[pkgs[i]], pkgs[i], dotDir, svgDir, sources, renderer)
+            }
+        }()
+    }
+    for i := range pkgs {
+        jobs <- i
+    }
+    close(jobs)
+    wg.Wait()
+
+    return results
+}
+
+/* -------------------------------------------------------
+ * renderPackage
+ * One unit of work: link sites, write DOT, render SVG.
+ * ------------------------------------------------------- */
+func renderPackage(
+    g        *DotGraph,
+    pkg      string,
+    dotDir   string,
+    svgDir   string,
+    sources  *sourceCache,
+    renderer Renderer,
+) *renderResult {
+    san     := sanitizePkg(pkg)
+    dotPath := filepath.Join(dotDir, san+".dot")
+    svgPath := filepath.Join(svgDir, san+".svg")
+    r       := &renderResult{Pkg: pkg}
+
+    tDotStart := time.Now()
+    r.Sites = linkEdgeSites(g, sources)
+    if err := g.WriteDOTToFile(dotPath); err != nil {
+        r.Err = fmt.Errorf("dot write: %w", err)
+        return r
+    }
+    r.Dot = time.Since(tDotStart)
+
+    tSvgStart := time.Now()
+    if err := renderSVG(g, dotPath, svgPath, renderer); err != nil {
+        r.Err = fmt.Errorf("svg gen: %w", err)
+    }
+    r.SVG = time.Since(tSvgStart)
+    return r
+}
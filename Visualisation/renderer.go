@@ -0,0 +1,88 @@
+package visualisation
+
+import (
+    "fmt"
+    "os/exec"
+)
+
+/* ============================================================================
+ * Renderer
+ * ----------------------------------------------------------------------------
+ * Selects how DOT graphs are turned into the SVGs embedded in the reports.
+ *
+ *   RendererAuto      Graphviz if `dot` is on the PATH, the built-in layout
+ *                     otherwise
+ *   RendererGraphviz  always run `dot -Tsvg`
+ *   RendererBuiltin   lay the graph out in Go (see layoutGraph); needs no
+ *                     external binary
+ *
+ * The DOT files are written either way.
+ * ============================================================================
+ */
+type Renderer int
+
+const (
+    RendererAuto Renderer = iota
+    RendererGraphviz
+    RendererBuiltin
+)
+
+func (r Renderer) String() string {
+    switch r {
+    case RendererAuto:     return "auto"
+    case RendererGraphviz: return "graphviz"
+    case RendererBuiltin:  return "builtin"
+    default:               return "unknown"
+    }
+}
+
+/* ============================================================================
+ * ParseRenderer
+ * ----------------------------------------------------------------------------
+ * Converts a flag value ("auto", "graphviz", "builtin") into a Renderer.
+ * ============================================================================
+ */
+func ParseRenderer(s string) (Renderer, error) {
+    switch s {
+    case "auto":     return RendererAuto, nil
+    case "graphviz": return RendererGraphviz, nil
+    case "builtin":  return RendererBuiltin, nil
+    }
+    return RendererAuto, fmt.Errorf("unknown renderer %q (want auto, graphviz or builtin)", s)
+}
+
+/* ============================================================================
+ * resolve
+ * ----------------------------------------------------------------------------
+ * Picks the concrete renderer for one report. Graphviz is looked up once
+ * here rather than failing once per package; an explicit graphviz request
+ * without the binary is an error.
+ * ============================================================================
+ */
+func (r Renderer) resolve() (Renderer, error) {
+    if r == RendererBuiltin {
+        return r, nil
+    }
+    if _, err := exec.LookPath("dot"); err != nil {
+        if r == RendererGraphviz {
+            return r, fmt.Errorf("graphviz not found: %w", err)
+        }
+        return RendererBuiltin, nil
+    }
+    return RendererGraphviz, nil
+}
+
+/* ============================================================================
+ * renderSVG
+ * ----------------------------------------------------------------------------
+ * Produces svgPath from g with a resolved renderer. Graphviz reads the DOT
+ * file at dotPath, which must already be written; the built-in layout
+ * works from g itself.
+ * ============================================================================
+ */
+func renderSVG(g *DotGraph, dotPath, svgPath string, r Renderer) error {
+    if r == RendererGraphviz {
+        return generateSVG(dotPath, svgPath)
+    }
+    return g.WriteSVGToFile(svgPath)
+}
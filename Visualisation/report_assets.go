@@ -0,0 +1,61 @@
+package visualisation
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+/* ============================================================================
+ * assetDir
+ * ----------------------------------------------------------------------------
+ * Folder next to a split report that holds its per-package assets, named
+ * like a browser's "save page": report.html -> report_files.
+ * ============================================================================
+ */
+func assetDir(htmlOut string) string {
+    return strings.TrimSuffix(htmlOut, filepath.Ext(htmlOut)) + "_files"
+}
+
+/* ============================================================================
+ * writeReportAssets
+ * ----------------------------------------------------------------------------
+ * Writes one script per package of svgMap into assetDir(htmlOut):
+ *
+ *   loadedPackage("<pkg>", "<svg>", [<call-site table>]);
+ *
+ * The index page loads a package's script when it is first selected.
+ * Scripts, unlike fetch(), also load when the report is opened from disk.
+ * Returns the src of each script relative to the index page.
+ * ============================================================================
+ */
+func writeReportAssets(
+    htmlOut string,
+    svgMap  map[string]string,
+    siteMap map[string][][]siteSnippet,
+) (map[string]string, error) {
+    dir := assetDir(htmlOut)
+    if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+        return nil, fmt.Errorf("mkdir %s: %w", dir, err)
+    }
+
+    files := make(map[string]string, len(svgMap))
+    for pkg, svg := range svgMap {
+        args, err := json.Marshal([]any{pkg, svg, siteMap[pkg]})
+        if err != nil {
+            return nil, fmt.Errorf("marshal %s: %w", pkg, err)
+        }
+        // [a,b,c] -> loadedPackage(a,b,c);
+        call := "loadedPackage(" + string(args[1:len(args)-1]) + ");\n"
+
+        name := sanitizePkg(pkg) + ".js"
+        if err := os.WriteFile(filepath.Join(dir, name), []byte(call), 0o644); err != nil {
+            return nil, err
+        }
+        files[pkg] = url.PathEscape(filepath.Base(dir)) + "/" + url.PathEscape(name)
+    }
+    return files, nil
+}
@@ -0,0 +1,74 @@
+package visualisation
+
+import (
+	"fmt"
+	"math"
+
+	cs_callgraph "callstat/CS-Callgraph"
+)
+
+/* ============================================================================
+ * NodeSizing
+ * ----------------------------------------------------------------------------
+ * Scales function nodes in the DOT output by a metric.
+ *
+ *   Metric  name shown in the tooltip, e.g. "betweenness"
+ *   Values  metric per node; nodes without a value keep the default size
+ * ============================================================================
+ */
+type NodeSizing struct {
+    Metric string
+    Values map[*cs_callgraph.Node]float64
+}
+
+/* ============================================================================
+ * sizeNodes
+ * ----------------------------------------------------------------------------
+ * Applies s to every package graph. Sizes grow with the square root of the
+ * value relative to the largest one, so a few large hubs do not shrink
+ * everything else to the default; the label font grows along.
+ * ============================================================================
+ */
+func sizeNodes(graphs map[string]*DotGraph, s *NodeSizing) {
+    if s == nil || len(s.Values) == 0 {
+        return
+    }
+    maxValue := 0.0
+    for _, v := range s.Values {
+        maxValue = max(maxValue, v)
+    }
+    if maxValue <= 0 {
+        return
+    }
+
+    // A node is drawn under its local ID or, in other packages, as ext_<id>
+    byID := make(map[string]float64, 2*len(s.Values))
+    for n, v := range s.Values {
+        byID[dotNodeID(n)] = v
+        if n.IfaceMethod == nil {
+            byID[convertNodeID(n.ID, ns_external)] = v
+        }
+    }
+
+    apply := func(dn *DotNode) {
+        v, ok := byID[dn.ID]
+        if !ok {
+            return
+        }
+        r := math.Sqrt(v / maxValue)
+        dn.Attrs["width"]    = fmt.Sprintf("%.2f", 0.75+2.25*r)
+        dn.Attrs["height"]   = fmt.Sprintf("%.2f", 0.5+1.0*r)
+        dn.Attrs["fontsize"] = fmt.Sprintf("%.0f", 14+14*r)
+        dn.Attrs["tooltip"] += fmt.Sprintf(" [%s %.4g]", s.Metric, v)
+    }
+    for _, g := range graphs {
+        for _, dn := range g.Nodes {
+            apply(dn)
+        }
+        for _, c := range g.Clusters {
+            for _, dn := range c.Nodes {
+                apply(dn)
+            }
+        }
+    }
+}
@@ -0,0 +1,99 @@
+package visualisation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Lines of source shown above and below a call site in the HTML report
+const snippetContext = 3
+
+/* ============================================================================
+ * siteSnippet
+ * ----------------------------------------------------------------------------
+ * One call site of an edge as embedded in the HTML report.
+ *
+ *   Pos    short "file.go:line" shown in the site list
+ *   File   full path of the source file
+ *   Line   1-based line of the call site
+ *   Start  1-based line number of Lines[0]
+ *   Lines  surrounding source lines (empty if the file is unreadable)
+ * ============================================================================
+ */
+type siteSnippet struct {
+	Pos   string   `json:"pos"`
+	File  string   `json:"file"`
+	Line  int      `json:"line"`
+	Start int      `json:"start"`
+	Lines []string `json:"lines"`
+}
+
+/* ============================================================================
+ * sourceCache
+ * ----------------------------------------------------------------------------
+ * Source files read while building snippets, split into lines. Each file is
+ * read at most once per report; unreadable files are cached as nil. Safe
+ * for use by the render workers.
+ * ============================================================================
+ */
+type sourceCache struct {
+	mu    sync.Mutex
+	files map[string][]string
+}
+
+func newSourceCache() *sourceCache {
+	return &sourceCache{files: make(map[string][]string)}
+}
+
+func (sc *sourceCache) lines(file string) []string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if lines, ok := sc.files[file]; ok {
+		return lines
+	}
+	var lines []string
+	if raw, err := os.ReadFile(file); err == nil {
+		lines = strings.Split(strings.ReplaceAll(string(raw), "\r\n", "\n"), "\n")
+	}
+	sc.files[file] = lines
+	return lines
+}
+
+/* ============================================================================
+ * linkEdgeSites
+ * ----------------------------------------------------------------------------
+ * Gives every edge of g that has call sites a URL="sites://<i>" attribute
+ * and returns the table those indices point into. Graphviz renders the URL
+ * as a link around the edge, which the report's click handler resolves
+ * against the table of the current package.
+ * ============================================================================
+ */
+func linkEdgeSites(g *DotGraph, sc *sourceCache) [][]siteSnippet {
+	var table [][]siteSnippet
+	for _, e := range g.Edges {
+		if len(e.Sites) == 0 {
+			continue
+		}
+		snippets := make([]siteSnippet, 0, len(e.Sites))
+		for _, pos := range e.Sites {
+			s := siteSnippet{
+				Pos:  fmt.Sprintf("%s:%d", filepath.Base(pos.Filename), pos.Line),
+				File: pos.Filename,
+				Line: pos.Line,
+			}
+			if lines := sc.lines(pos.Filename); pos.Line > 0 && pos.Line <= len(lines) {
+				s.Start = max(1, pos.Line-snippetContext)
+				end    := min(len(lines), pos.Line+snippetContext)
+				s.Lines = lines[s.Start-1 : end]
+			}
+			snippets = append(snippets, s)
+		}
+		e.Attrs["URL"] = "sites://" + strconv.Itoa(len(table))
+		table = append(table, snippets)
+	}
+	return table
+}
@@ -21,6 +21,15 @@ const (
     ns_external     NodeStyle   = "external"
     ns_interface    NodeStyle   = "interface"
     ns_panic        NodeStyle   = "panic"
+    ns_asm          NodeStyle   = "asm"
+    ns_cgo          NodeStyle   = "cgo"
+    ns_linkname     NodeStyle   = "linkname"
+    ns_bodiless     NodeStyle   = "bodiless"
+    ns_sink         NodeStyle   = "sink"
+    ns_diff_added   NodeStyle   = "diff_added"
+    ns_diff_removed NodeStyle   = "diff_removed"
+    ns_diff_reach   NodeStyle   = "diff_reachable"
+    ns_diff_dead    NodeStyle   = "diff_dead"
 
     es_call       	EdgeStyle   = "call"
     es_go         	EdgeStyle   = "go"
@@ -30,7 +39,11 @@ const (
     es_send			EdgeStyle   = "send"
     es_receive		EdgeStyle   = "receive"
     es_interface    EdgeStyle   = "interface"
+    es_dispatch     EdgeStyle   = "dispatch"
+    es_reflect      EdgeStyle   = "reflect"
     es_default    	EdgeStyle	= "default"
+    es_diff_added   EdgeStyle   = "diff_added"
+    es_diff_removed EdgeStyle   = "diff_removed"
 )
 
 /* ============================================================================
@@ -39,26 +52,19 @@ const (
  * Represents the JSON structure used to configure visual styles.
  *
  * Structure:
- *   - NodeStyles : map[nodeType] -> map[attr]value
- *   - EdgeStyles : map[edgeType] -> map[attr]value
- *   - Cluster    : shared attributes for clusters
+ *   - NodeStyles   : map[nodeType] -> map[attr]value
+ *   - EdgeStyles   : map[edgeType] -> map[attr]value
+ *   - Cluster      : shared attributes for clusters
+ *   - CycleCluster : laid over Cluster for recursion cycles (optional)
  * ============================================================================
  */
 type StyleConfig struct {
-    NodeStyles map[string]map[string]string `json:"nodeStyles"`
-    EdgeStyles map[string]map[string]string `json:"edgeStyles"`
-    Cluster    map[string]string            `json:"cluster"`
+    NodeStyles   map[string]map[string]string `json:"nodeStyles"`
+    EdgeStyles   map[string]map[string]string `json:"edgeStyles"`
+    Cluster      map[string]string            `json:"cluster"`
+    CycleCluster map[string]string            `json:"cycleCluster"`
 }
 
-/* ============================================================================
- * Global State
- * ----------------------------------------------------------------------------
- * Holds the currently loaded style configuration.
- * Must be initialized before any graph building occurs.
- * ============================================================================
- */
-var global_styles *StyleConfig
-
 //go:embed format.json
 var defaultStyleJSON []byte
 
@@ -73,9 +79,9 @@ var defaultStyleJSON []byte
  *   - EdgeStyles: "call"
  * ============================================================================
  */
-func validate() error {
+func validate(cfg *StyleConfig) error {
 
-    if global_styles == nil {
+    if cfg == nil {
         return fmt.Errorf("styles not loaded")
     }
 
@@ -83,7 +89,7 @@ func validate() error {
      * Required Node Styles
      * ------------------------------------------------------- */
     for _, key := range []string{"normal", "external"} {
-        if _, ok := global_styles.NodeStyles[key]; !ok {
+        if _, ok := cfg.NodeStyles[key]; !ok {
             return fmt.Errorf("missing required node style: %s", key)
         }
     }
@@ -92,7 +98,7 @@ func validate() error {
      * Required Edge Styles
      * ------------------------------------------------------- */
     for _, key := range []string{"call"} {
-        if _, ok := global_styles.EdgeStyles[key]; !ok {
+        if _, ok := cfg.EdgeStyles[key]; !ok {
             return fmt.Errorf("missing required edge style: %s", key)
         }
     }
@@ -100,60 +106,79 @@ func validate() error {
     return nil
 }
 
+/* ============================================================================
+ * resolveStyles
+ * ----------------------------------------------------------------------------
+ * Returns the configuration a report generator should draw with: st itself
+ * once validated, or the embedded defaults when st is nil, so callers
+ * without a custom style file can pass nil.
+ * ============================================================================
+ */
+func resolveStyles(st *StyleConfig) (*StyleConfig, error) {
+    if st == nil {
+        return LoadInternalStyles()
+    }
+    if err := validate(st); err != nil {
+        return nil, err
+    }
+    return st, nil
+}
+
 /* ============================================================================
  * LoadInternalStyles
  * ----------------------------------------------------------------------------
- * Loads the embedded default JSON configuration into global_styles.
- * This should typically be called at startup.
+ * Parses the embedded default JSON configuration. Each call returns a fresh
+ * StyleConfig, so callers may adjust it without affecting anyone else.
  * ============================================================================
  */
-func LoadInternalStyles() error {
+func LoadInternalStyles() (*StyleConfig, error) {
 
     var cfg StyleConfig
 
     if err := json.Unmarshal(defaultStyleJSON, &cfg); err != nil {
-        return fmt.Errorf("failed to parse embedded JSON: %w", err)
+        return nil, fmt.Errorf("failed to parse embedded JSON: %w", err)
     }
 
-    global_styles = &cfg
-    return validate()
+    if err := validate(&cfg); err != nil {
+        return nil, err
+    }
+    return &cfg, nil
 }
 
 /* ============================================================================
  * LoadStyles
  * ----------------------------------------------------------------------------
- * Loads a JSON style configuration from disk and replaces global_styles.
+ * Loads a JSON style configuration from disk, to be passed to the report
+ * generators.
  *
  * Behaviour:
  *   1. Opens file from given path
  *   2. Decodes JSON into StyleConfig
  *   3. Validates required fields
- *   4. Sets global_styles if valid
+ *   4. Returns it if valid
  * ============================================================================
  */
-func LoadStyles(path string) error {
+func LoadStyles(path string) (*StyleConfig, error) {
 
     f, err := os.Open(path)
     if err != nil {
-        return fmt.Errorf("could not open config file at %s: %w", path, err)
+        return nil, fmt.Errorf("could not open config file at %s: %w", path, err)
     }
     defer f.Close()
 
     var cfg StyleConfig
 
     if err := json.NewDecoder(f).Decode(&cfg); err != nil {
-        return fmt.Errorf("failed to decode JSON: %w", err)
+        return nil, fmt.Errorf("failed to decode JSON: %w", err)
     }
 
-    global_styles = &cfg
-
     /* -------------------------------------------------------
      * Validation
      * ------------------------------------------------------- */
-    if err := validate(); err != nil {
-        return err
+    if err := validate(&cfg); err != nil {
+        return nil, err
     }
 
     fmt.Printf("Successfully loaded styles from: %s\n", path)
-    return nil
-}
\ No newline at end of file
+    return &cfg, nil
+}
@@ -0,0 +1,297 @@
+package visualisation
+
+import (
+    "bufio"
+    "fmt"
+    "html"
+    "io"
+    "math"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// Default font of the built-in renderer; Graphviz uses Times
+const svgFont = "Helvetica,Arial,sans-serif"
+
+/* ============================================================================
+ * WriteSVGToFile
+ * ----------------------------------------------------------------------------
+ * Lays g out with layoutGraph and writes it as SVG, without Graphviz. The
+ * output follows the structure of `dot -Tsvg` closely enough for the
+ * reports: clusters, edges and nodes are <g> groups with a <title>, and
+ * URL attributes (pkg://, sites://) become <a xlink:href> links.
+ *
+ * Styling comes from the same attributes the DOT file carries: shape (box,
+ * box3d, ellipse, plaintext), style (filled, dashed, dotted, bold, invis),
+ * color, fillcolor, fontcolor, fontsize, penwidth, arrowhead and label.
+ * ============================================================================
+ */
+func (g *DotGraph) WriteSVGToFile(filename string) error {
+    f, err := os.Create(filename)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    w := bufio.NewWriter(f)
+    writeSVG(w, layoutGraph(g))
+    return w.Flush()
+}
+
+func writeSVG(w io.Writer, l *graphLayout) {
+    fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`)
+    fmt.Fprintf(w, `<svg width="%spt" height="%spt" viewBox="0 0 %s %s" `+
+        `xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">`+"\n",
+        num(l.w), num(l.h), num(l.w), num(l.h))
+    fmt.Fprintln(w, `<g id="graph0" class="graph">`)
+    fmt.Fprintf(w, `<rect x="0" y="0" width="%s" height="%s" fill="white"/>`+"\n", num(l.w), num(l.h))
+
+    for _, c := range l.clusters {
+        if c.x1 > c.x0 {
+            writeSVGCluster(w, c)
+        }
+    }
+    for _, e := range l.edges {
+        writeSVGEdge(w, e)
+    }
+    for _, n := range l.nodes {
+        writeSVGNode(w, n)
+    }
+
+    fmt.Fprintln(w, "</g>")
+    fmt.Fprintln(w, "</svg>")
+}
+
+/* -------------------------------------------------------
+ * writeSVGCluster
+ * ------------------------------------------------------- */
+func writeSVGCluster(w io.Writer, c *layoutCluster) {
+    st := parseStyle(c.dc.Attrs)
+    if st.invis {
+        return
+    }
+    fill := "none"
+    if st.filled {
+        fill = firstOf(c.dc.Attrs["fillcolor"], c.dc.Attrs["color"], "lightgrey")
+    }
+
+    fmt.Fprintln(w, `<g class="cluster">`)
+    fmt.Fprintf(w, "<title>%s</title>\n", esc(c.dc.ID))
+    fmt.Fprintf(w, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s" stroke="%s"%s/>`+"\n",
+        num(c.x0), num(c.y0), num(c.x1-c.x0), num(c.y1-c.y0),
+        esc(fill), esc(firstOf(c.dc.Attrs["color"], "black")), st.strokeAttrs())
+    if label := clusterLabel(c.dc); label != "" {
+        writeSVGText(w, labelLines(label), (c.x0+c.x1)/2, c.y0+layoutClusterPad+14, 14,
+            firstOf(c.dc.Attrs["fontcolor"], "black"))
+    }
+    fmt.Fprintln(w, "</g>")
+}
+
+/* -------------------------------------------------------
+ * writeSVGNode
+ * ------------------------------------------------------- */
+func writeSVGNode(w io.Writer, n *layoutNode) {
+    attrs := n.dn.Attrs
+    st    := parseStyle(attrs)
+    if st.invis {
+        return
+    }
+    stroke := firstOf(attrs["color"], "black")
+    fill   := "none"
+    if st.filled {
+        fill = firstOf(attrs["fillcolor"], attrs["color"], "lightgrey")
+    }
+
+    fmt.Fprintln(w, `<g class="node">`)
+    fmt.Fprintf(w, "<title>%s</title>\n", esc(firstOf(attrs["tooltip"], nodeLabel(n.dn))))
+    url := attrs["URL"]
+    if url != "" {
+        fmt.Fprintf(w, `<a xlink:href="%s" xlink:title="%s">`+"\n", esc(url), esc(attrs["tooltip"]))
+    }
+
+    paint := fmt.Sprintf(`fill="%s" stroke="%s"%s`, esc(fill), esc(stroke), st.strokeAttrs())
+    switch attrs["shape"] {
+    case "box", "rect", "rectangle", "square":
+        fmt.Fprintf(w, `<rect x="%s" y="%s" width="%s" height="%s" %s/>`+"\n",
+            num(n.x), num(n.y), num(n.w), num(n.h), paint)
+    case "box3d":
+        const d = 4.0
+        fmt.Fprintf(w, `<rect x="%s" y="%s" width="%s" height="%s" %s/>`+"\n",
+            num(n.x), num(n.y+d), num(n.w-d), num(n.h-d), paint)
+        fmt.Fprintf(w, `<path d="M%s,%s L%s,%s L%s,%s L%s,%s L%s,%s M%s,%s L%s,%s" fill="none" stroke="%s"%s/>`+"\n",
+            num(n.x), num(n.y+d), num(n.x+d), num(n.y), num(n.x+n.w), num(n.y),
+            num(n.x+n.w), num(n.y+n.h-d), num(n.x+n.w-d), num(n.y+n.h),
+            num(n.x+n.w-d), num(n.y+d), num(n.x+n.w), num(n.y),
+            esc(stroke), st.strokeAttrs())
+    case "plaintext", "plain", "none":
+    default:
+        fmt.Fprintf(w, `<ellipse cx="%s" cy="%s" rx="%s" ry="%s" %s/>`+"\n",
+            num(n.cx()), num(n.cy()), num(n.w/2), num(n.h/2), paint)
+    }
+
+    size  := fontSize(attrs)
+    lines := labelLines(nodeLabel(n.dn))
+    top   := n.cy() - float64(len(lines)-1)*size*1.2/2 + size*0.35
+    writeSVGText(w, lines, n.cx(), top, size, firstOf(attrs["fontcolor"], "black"))
+
+    if url != "" {
+        fmt.Fprintln(w, "</a>")
+    }
+    fmt.Fprintln(w, "</g>")
+}
+
+/* -------------------------------------------------------
+ * writeSVGEdge
+ * Draws the curve routed by layoutGraph, its arrowhead and
+ * label.
+ * ------------------------------------------------------- */
+func writeSVGEdge(w io.Writer, e *layoutEdge) {
+    attrs := e.de.Attrs
+    st    := parseStyle(attrs)
+    if st.invis {
+        return
+    }
+    color := firstOf(attrs["color"], "black")
+
+    p := e.curve
+
+    // The arrowhead ends at the node; the line stops at its base
+    tip := p[3]
+    ux, uy := tip[0]-p[2][0], tip[1]-p[2][1]
+    if d := math.Hypot(ux, uy); d > 0 {
+        ux, uy = ux/d, uy/d
+    } else {
+        ux, uy = 1, 0
+    }
+    head, length := arrowhead(firstOf(attrs["arrowhead"], "normal"), tip, ux, uy, esc(color))
+    p[3] = [2]float64{tip[0] - length*ux, tip[1] - length*uy}
+
+    fmt.Fprintln(w, `<g class="edge">`)
+    fmt.Fprintf(w, "<title>%s</title>\n", esc(firstOf(attrs["tooltip"], e.de.From+"->"+e.de.To)))
+    url := attrs["URL"]
+    if url != "" {
+        fmt.Fprintf(w, `<a xlink:href="%s" xlink:title="%s">`+"\n", esc(url), esc(attrs["tooltip"]))
+    }
+
+    fmt.Fprintf(w, `<path d="M%s,%s C%s,%s %s,%s %s,%s" fill="none" stroke="%s"%s/>`+"\n",
+        num(p[0][0]), num(p[0][1]), num(p[1][0]), num(p[1][1]),
+        num(p[2][0]), num(p[2][1]), num(p[3][0]), num(p[3][1]),
+        esc(color), st.strokeAttrs())
+    if head != "" {
+        fmt.Fprintln(w, head)
+    }
+    if label := attrs["label"]; label != "" {
+        // Midpoint of the cubic curve
+        mx := (p[0][0] + 3*p[1][0] + 3*p[2][0] + p[3][0]) / 8
+        my := (p[0][1] + 3*p[1][1] + 3*p[2][1] + p[3][1]) / 8
+        writeSVGText(w, labelLines(label), mx, my-4, 10, firstOf(attrs["fontcolor"], "black"))
+    }
+
+    if url != "" {
+        fmt.Fprintln(w, "</a>")
+    }
+    fmt.Fprintln(w, "</g>")
+}
+
+/* -------------------------------------------------------
+ * arrowhead
+ * Returns the SVG element for the named Graphviz arrow
+ * with its tip at tip pointing along (ux, uy), and its
+ * length. Names starting with "o" and "empty" / "open"
+ * are drawn unfilled.
+ * ------------------------------------------------------- */
+func arrowhead(name string, tip [2]float64, ux, uy float64, color string) (string, float64) {
+    at := func(back, side float64) string {
+        return num(tip[0]-back*ux-side*uy) + "," + num(tip[1]-back*uy+side*ux)
+    }
+    fill := color
+    if name == "empty" || name == "open" || (len(name) > 1 && name[0] == 'o') {
+        fill = "white"
+    }
+    paint   := fmt.Sprintf(`fill="%s" stroke="%s"`, fill, color)
+    polygon := func(points ...string) string {
+        return `<polygon points="` + strings.Join(points, " ") + `" ` + paint + `/>`
+    }
+
+    switch strings.TrimPrefix(name, "o") {
+    case "none":
+        return "", 0
+    case "dot":
+        c := strings.Split(at(4, 0), ",")
+        return `<circle cx="` + c[0] + `" cy="` + c[1] + `" r="4" ` + paint + `/>`, 8
+    case "diamond":
+        return polygon(at(0, 0), at(6, 4), at(12, 0), at(6, -4)), 12
+    case "tee":
+        return polygon(at(1, 5), at(4, 5), at(4, -5), at(1, -5)), 4
+    case "inv":
+        return polygon(at(0, 4), at(0, -4), at(10, 0)), 10
+    case "vee":
+        return polygon(at(0, 0), at(10, 5), at(6, 0), at(10, -5)), 6
+    }
+    return polygon(at(0, 0), at(10, 4), at(10, -4)), 10
+}
+
+/* -------------------------------------------------------
+ * writeSVGText
+ * One centred <text> per label line, starting at baseline
+ * y of the first line.
+ * ------------------------------------------------------- */
+func writeSVGText(w io.Writer, lines []string, x, y, size float64, color string) {
+    for i, line := range lines {
+        fmt.Fprintf(w, `<text text-anchor="middle" x="%s" y="%s" font-family="%s" font-size="%s" fill="%s">%s</text>`+"\n",
+            num(x), num(y+float64(i)*size*1.2), svgFont, num(size), esc(color), esc(line))
+    }
+}
+
+/* -------------------------------------------------------
+ * svgStyle
+ * The parts of a Graphviz style list the writer honours.
+ * ------------------------------------------------------- */
+type svgStyle struct {
+    filled   bool
+    invis    bool
+    dash     string
+    penwidth float64
+}
+
+func parseStyle(attrs map[string]string) svgStyle {
+    st := svgStyle{penwidth: 1}
+    for _, part := range strings.Split(attrs["style"], ",") {
+        switch strings.TrimSpace(part) {
+        case "filled": st.filled = true
+        case "invis":  st.invis = true
+        case "dashed": st.dash = "5,2"
+        case "dotted": st.dash = "1,5"
+        case "bold":   st.penwidth = 2
+        }
+    }
+    if v, err := strconv.ParseFloat(attrs["penwidth"], 64); err == nil {
+        st.penwidth = v
+    }
+    return st
+}
+
+func (st svgStyle) strokeAttrs() string {
+    s := ""
+    if st.penwidth != 1 {
+        s += fmt.Sprintf(` stroke-width="%s"`, num(st.penwidth))
+    }
+    if st.dash != "" {
+        s += fmt.Sprintf(` stroke-dasharray="%s"`, st.dash)
+    }
+    return s
+}
+
+func firstOf(values ...string) string {
+    for _, v := range values {
+        if v != "" {
+            return v
+        }
+    }
+    return ""
+}
+
+func esc(s string) string { return html.EscapeString(s) }
+
+func num(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
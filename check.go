@@ -0,0 +1,114 @@
+package main
+
+import (
+	budget "callstat/Budget"
+	callstat "callstat/Callstat"
+	rules "callstat/Rules"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+/* ============================================================================
+ * check subcommand
+ * ----------------------------------------------------------------------------
+ * Checks the architecture rules of -rules and the thresholds of -budget
+ * against the project and exits non-zero if any of them fails, so they
+ * can gate merges:
+ *
+ *   callstat check [-rules rules.json] [-budget budget.json] [flags]
+ *
+ * Results go to stdout as text, or as JSON with -json; progress output
+ * goes to stderr. Returns the exit code: 0 pass, 1 a rule is violated or
+ * a budget fails, 2 a budget could not be evaluated.
+ * ============================================================================
+ */
+type checkAnswer struct {
+    Rules   *rules.Report    `json:"rules,omitempty"`
+    Budgets []*budget.Result `json:"budgets,omitempty"`
+}
+
+func runCheck(args []string, cfg callstat.Config, budgets *budget.Set, json bool) (int, error) {
+    if len(args) != 0 || (cfg.Rules == nil && budgets == nil) {
+        return 0, fmt.Errorf("usage: callstat check [-rules <file>] [-budget <file>] [flags]")
+    }
+
+    cfg.NoStats = budgets == nil
+    res, err := analyzeSilenced(cfg)
+    if err != nil {
+        return 0, err
+    }
+    answer := checkAnswer{Rules: res.Rules}
+    code   := 0
+    if res.Rules != nil && res.Rules.Violations > 0 {
+        code = 1
+    }
+    if budgets != nil {
+        if answer.Budgets, err = budget.Evaluate(budgets, res.Report); err != nil {
+            return 0, err
+        }
+        code = max(code, int(budget.Worst(answer.Budgets)))
+    }
+
+    if json {
+        return code, printJSON(answer)
+    }
+    if answer.Rules != nil {
+        printRules(answer.Rules)
+    }
+    if answer.Budgets != nil {
+        printBudgets(answer.Budgets)
+    }
+    return code, nil
+}
+
+/* -------------------------------------------------------
+ * printRules
+ * One line per rule, then every violation with its chain.
+ * ------------------------------------------------------- */
+func printRules(r *rules.Report) {
+    for _, rr := range r.Rules {
+        status := "ok"
+        if n := len(rr.Violations); n > 0 {
+            status = fmt.Sprintf("%d violation(s)", n)
+        }
+        fmt.Printf("[rules] %s (%s %v → %v): %s\n", rr.Name, rr.Kind, rr.From, rr.To, status)
+        for _, v := range rr.Violations {
+            fmt.Printf("  %s → %s\n", v.Caller, v.Callee)
+            for _, step := range v.Chain[1:] {
+                label := step.Kind
+                if step.Site != "" {
+                    label += " " + step.Site
+                }
+                fmt.Printf("      -[%s]-> %s\n", label, step.Function)
+            }
+        }
+    }
+    if r.Violations > 0 {
+        fmt.Fprintf(os.Stderr, "[rules] %d violation(s) of %d rule(s)\n", r.Violations, len(r.Rules))
+    }
+}
+
+/* -------------------------------------------------------
+ * printBudgets
+ * The pass/fail table.
+ * ------------------------------------------------------- */
+func printBudgets(results []*budget.Result) {
+    w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "[budget] RESULT\tBUDGET\tVALUE")
+    failed := 0
+    for _, r := range results {
+        value := r.Value
+        if r.Outcome == budget.OutcomeError {
+            value = r.Error
+        }
+        if r.Outcome != budget.OutcomePass {
+            failed++
+        }
+        fmt.Fprintf(w, "[budget] %s\t%s\t%s\n", r.Outcome, r.Name, value)
+    }
+    w.Flush()
+    if failed > 0 {
+        fmt.Fprintf(os.Stderr, "[budget] %d of %d budget(s) not met\n", failed, len(results))
+    }
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	callstat "callstat/Callstat"
+	diff "callstat/Diff"
+	export "callstat/Export"
+	visualisation "callstat/Visualisation"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/* ============================================================================
+ * diff subcommand
+ * ----------------------------------------------------------------------------
+ * Compares two analysis results of the same project:
+ *
+ *   callstat diff [flags] <old> <new>
+ *
+ * Each side is either a JSON graph export (-export graph.json) or a project
+ * directory, which is analysed with the given flags (so both directories
+ * use the same settings). The summary goes to stdout as text, or as JSON
+ * with -json; with -report the HTML view is written as well.
+ * ============================================================================
+ */
+type diffOptions struct {
+    json     bool
+    report   string
+    dotDir   string
+    svgDir   string
+    renderer visualisation.Renderer
+}
+
+func runDiff(args []string, cfg callstat.Config, opts diffOptions) error {
+    if len(args) != 2 {
+        return fmt.Errorf("usage: callstat diff [flags] <old> <new>")
+    }
+
+    docs := make([]*export.Document, 2)
+    for i, arg := range args {
+        doc, err := loadDiffSide(arg, cfg)
+        if err != nil {
+            return err
+        }
+        docs[i] = doc
+    }
+    if docs[0].K != docs[1].K {
+        fmt.Fprintf(os.Stderr, "[warn] comparing graphs of different context depth (k=%d vs k=%d)\n", docs[0].K, docs[1].K)
+    }
+
+    result := diff.Compare(docs[0], docs[1], args[0], args[1])
+
+    if opts.report != "" {
+        if err := visualisation.GenerateDiffReport(result, opts.dotDir, opts.svgDir, opts.report, opts.renderer, nil); err != nil {
+            return err
+        }
+        fmt.Fprintf(os.Stderr, "[info] diff report written to %s\n", opts.report)
+    }
+    if opts.json {
+        return printJSON(result)
+    }
+    printDiff(result)
+    return nil
+}
+
+/* -------------------------------------------------------
+ * loadDiffSide
+ * Reads a JSON export, or analyses a directory and builds
+ * the same Document -export would write for it.
+ * ------------------------------------------------------- */
+func loadDiffSide(arg string, cfg callstat.Config) (*export.Document, error) {
+    info, err := os.Stat(arg)
+    if err != nil {
+        return nil, err
+    }
+    if !info.IsDir() {
+        return export.ReadFile(arg)
+    }
+
+    cfg.Dir = arg
+    res, err := analyzeQuiet(cfg)
+    if err != nil {
+        return nil, fmt.Errorf("analyse %s: %w", arg, err)
+    }
+    return export.Build(res.View, res.DepthMap, res.ProjectRoot), nil
+}
+
+func printDiff(r *diff.Result) {
+    s := r.Summary
+    fmt.Printf("diff %s → %s\n", r.Old, r.New)
+    fmt.Printf("  functions        %d → %d (+%d -%d)\n", s.OldNodes, s.NewNodes, s.AddedNodes, s.RemovedNodes)
+    fmt.Printf("  edges            %d → %d (+%d -%d)\n", s.OldEdges, s.NewEdges, s.AddedEdges, s.RemovedEdges)
+    fmt.Printf("  newly reachable  %d\n", s.NewlyReachable)
+    fmt.Printf("  newly dead       %d\n", s.NewlyDead)
+
+    printNodes := func(title, mark string, nodes []*diff.NodeChange) {
+        if len(nodes) == 0 {
+            return
+        }
+        fmt.Printf("\n%s:\n", title)
+        for _, n := range nodes {
+            fmt.Printf("  %s %s\n", mark, n.Name)
+        }
+    }
+    printEdges := func(title, mark string, edges []*diff.EdgeChange) {
+        if len(edges) == 0 {
+            return
+        }
+        fmt.Printf("\n%s:\n", title)
+        for _, e := range edges {
+            sites := make([]string, len(e.Positions))
+            for i, p := range e.Positions {
+                sites[i] = p.String()
+            }
+            fmt.Printf("  %s %s -[%s]-> %s\n", mark, e.Caller, strings.Join(append([]string{e.Kind}, sites...), " "), e.Callee)
+        }
+    }
+
+    printNodes("added functions", "+", r.AddedNodes)
+    printNodes("removed functions", "-", r.RemovedNodes)
+    printNodes("newly reachable", "+", r.NewlyReachable)
+    printNodes("newly dead", "-", r.NewlyDead)
+    printEdges("added edges", "+", r.AddedEdges)
+    printEdges("removed edges", "-", r.RemovedEdges)
+}
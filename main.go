@@ -1,22 +1,20 @@
 package main
 
 import (
-	"bufio"
+	budget "callstat/Budget"
 	cs_callgraph "callstat/CS-Callgraph"
-	stats "callstat/Statistics"
+	callstat "callstat/Callstat"
+	export "callstat/Export"
+	rules "callstat/Rules"
 	visualisation "callstat/Visualisation"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
+	"runtime"
 	"strings"
-	"sync"
 	"time"
-
-	"golang.org/x/tools/go/packages"
-	"golang.org/x/tools/go/ssa"
-	"golang.org/x/tools/go/ssa/ssautil"
 )
 
 /* ============================================================================
@@ -33,93 +31,35 @@ func (s *stringSlice) String() string     { return strings.Join(*s, ", ") }
 func (s *stringSlice) Set(v string) error { *s = append(*s, v); return nil }
 
 /* ============================================================================
- * GetModuleName
- * ----------------------------------------------------------------------------
- * Climbs the directory tree from targetDir looking for a go.mod file.
- * Returns the declared module name, or "" if none is found.
- *
- * Uses os.ReadFile to avoid a defer-inside-loop leak.
- * ============================================================================
- */
-func GetModuleName(targetDir string) string {
-    absDir, err := filepath.Abs(targetDir)
-    if err != nil {
-        return ""
-    }
-    for curr := absDir; ; curr = filepath.Dir(curr) {
-        data, err := os.ReadFile(filepath.Join(curr, "go.mod"))
-        if err == nil {
-            scanner := bufio.NewScanner(strings.NewReader(string(data)))
-            if scanner.Scan() {
-                line := strings.TrimSpace(scanner.Text())
-                return strings.TrimPrefix(line, "module ")
-            }
-        }
-        if parent := filepath.Dir(curr); parent == curr {
-            break
-        }
-    }
-    return ""
-}
-
-
-/* ============================================================================
- * matchesPattern
- * ----------------------------------------------------------------------------
- * Reports whether pkgPath matches any pattern in the list.
- *
- * Two match modes:
- *   "runtime"   exact match - only "runtime" itself
- *   "runtime/"  prefix match - "runtime", "runtime/internal", etc.
- * ============================================================================
- */
-func matchesPattern(pkgPath string, patterns []string) bool {
-    for _, p := range patterns {
-        if strings.HasSuffix(p, "/") {
-            base := strings.TrimSuffix(p, "/")
-            if pkgPath == base || strings.HasPrefix(pkgPath, base+"/") {
-                return true
-            }
-        } else if pkgPath == p {
-            return true
-        }
-    }
-    return false
-}
-
-/* ============================================================================
- * buildSkipMap
+ * main
  * ----------------------------------------------------------------------------
- * Expands a list of patterns (and optionally all stdlib packages) into a
- * concrete map[pkgPath]struct{} by testing every known package path.
+ * Without a subcommand, analyses the project and writes the reports. With
+ * callers / callees / path as first argument, answers that query instead
+ * (see query.go); with diff, compares two results (see diff.go); with
+ * check, checks the -rules and -budget files and exits non-zero if any
+ * fails (see check.go). The analysis flags follow the subcommand. With
+ * -budget, a plain run also exits with the budget outcome once every
+ * output is written.
  * ============================================================================
  */
-func buildSkipMap(
-    patterns      []string,
-    excludeStdlib bool,
-    allPkgPaths   []string,
-) map[string]struct{} {
-    result := make(map[string]struct{})
-    for _, path := range allPkgPaths {
-        patternMatch := matchesPattern(path, patterns)
-        stdlibMatch  := excludeStdlib && cs_callgraph.IsStdlib(path)
-        if patternMatch || stdlibMatch {
-            result[path] = struct{}{}
+func main() {
+    command := ""
+    if len(os.Args) > 1 {
+        if _, ok := queryCommands[os.Args[1]]; ok || os.Args[1] == "diff" || os.Args[1] == "check" {
+            command = os.Args[1]
+            os.Args = append(os.Args[:1], os.Args[2:]...)
         }
     }
-    return result
-}
 
-/* ============================================================================
- * main
- * ============================================================================
- */
-func main() {
     /* -------------------------------------------------------
      * Flags
      * ------------------------------------------------------- */
     var skipCGPatterns  stringSlice
     var skipVisPatterns stringSlice
+    var libPatterns     stringSlice
+    var entryNames      stringSlice
+    var exportPaths     stringSlice
+    var sinkPatterns    stringSlice
 
     depthFlag := flag.Int("depth", 2,
         "Depth of external package traversal (-1 = unlimited)")
@@ -131,6 +71,14 @@ func main() {
         "Directory for intermediate DOT files")
     svgDir := flag.String("svg-dir", "./output/svg",
         "Directory for intermediate SVG files")
+    workers := flag.Int("workers", runtime.NumCPU(),
+        "Number of packages rendered to DOT/SVG in parallel")
+    splitFlag := flag.Bool("split", false,
+        "Write the report as an index page plus one asset per package "+
+            "(in <report>_files/), loaded on demand - for very large projects")
+    rendererFlag := flag.String("renderer", "auto",
+        "SVG renderer: auto (graphviz if installed, else builtin), "+
+            "graphviz (the dot binary) or builtin (no external binary)")
     statsOut := flag.String("stats", "./output/callgraph_report.json",
         "Path for the stats JSON output")
     noStdlib := flag.Bool("no-stdlib", false,
@@ -140,100 +88,268 @@ func main() {
         "Disable statistics calculation and JSON output")
     noVis := flag.Bool("no-vis", false, 
         "Disable DOT/SVG generation and visualization parts")
+    flag.Var(&exportPaths, "export",
+        "Write the whole call graph to this file; format by extension: "+
+            ".json, .graphml or .gexf (repeatable)")
+    sarifOut := flag.String("sarif", "",
+        "Write unreachable functions, reached sinks, recursion cycles and "+
+            "rule violations as SARIF 2.1.0 to this file")
+    rulesFile := flag.String("rules", "",
+        "Architecture rules file (JSON) of allow/deny constraints between "+
+            "package patterns; check exits 1 on violations")
+    budgetFile := flag.String("budget", "",
+        "Budget file (JSON) of threshold expressions over the stats JSON; "+
+            "exits 1 if one fails, 2 if one cannot be evaluated")
     
     mainEntry := flag.String("main", "",
         "Fully qualified main function to use as entry point "+
             "(e.g. 'github.com/you/repo/cmd/serve.main'); "+
             "defaults to automatic detection")
+    allMains := flag.Bool("all-mains", false,
+        "Use every main function in the project as an entry point "+
+            "instead of selecting one")
+    withInits := flag.Bool("inits", false,
+        "Also use the init function of every in-depth package as an entry point")
+    flag.Var(&entryNames, "entry",
+        "Additional fully qualified entry function "+
+            "(e.g. 'github.com/you/repo/worker.Run'; repeatable)")
             
+    ifaceFlag := flag.String("iface", "cha",
+        "Interface dispatch resolution: none, cha (class hierarchy) "+
+            "or rta (rapid type analysis, instantiated types only)")
+    funcValFlag := flag.String("funcval", "syntactic",
+        "Function value call resolution: syntactic (literal callees only) "+
+            "or vta (whole-program variable type analysis)")
+    genericsFlag := flag.String("generics", "origin",
+        "Generic functions: origin (one node per generic function) or "+
+            "instances (one node per instantiation, grouped under the origin)")
+    kFlag := flag.Int("k", 0,
+        "Call-site context depth for k-CFA context expansion (0 = off). "+
+            "Callbacks are only split per context with -funcval vta")
+    viewFlag := flag.String("view", "collapsed",
+        "Graph used for stats and DOT output when -k > 0: "+
+            "collapsed (one node per function) or expanded (one per context)")
+
+    libMode := flag.Bool("lib", false,
+        "Library mode: use every exported function and method of the "+
+            "selected packages as roots instead of a main function")
+    flag.Var(&libPatterns, "lib-pkg",
+        "Packages analysed in library mode (repeatable; trailing / = prefix "+
+            "match); defaults to every package under the project root")
+
+    flag.Var(&sinkPatterns, "sink",
+        "Sink function (e.g. 'os/exec.Command') or package (trailing / = "+
+            "prefix match); reports which entry points reach it (repeatable)")
+
+    centralityFlag := flag.Bool("centrality", false,
+        "Compute fan-in, fan-out, caller packages, betweenness and PageRank "+
+            "per in-depth function (stats JSON 'centrality'; slow on large graphs)")
+    sizeByFlag := flag.String("size-by", "none",
+        "Scale graph nodes by a centrality metric: none, fanin, fanout, "+
+            "callerpkgs, betweenness or pagerank (implies -centrality)")
+    funcMetrics := flag.Bool("func-metrics", false,
+        "Add per-function code metrics (SSA blocks, instructions, cyclomatic "+
+            "complexity, line span, kind) joined with reachability (stats JSON 'functions')")
+
     flag.Var(&skipCGPatterns, "skip-cg",
         "Exclude from callgraph (repeatable; trailing / = prefix match)")
     flag.Var(&skipVisPatterns, "skip-vis",
         "Exclude from visualisation (repeatable; trailing / = prefix match)")
 
+    levelsFlag := flag.Int("levels", 1,
+        "callers / callees: how many calls away to search (-1 = unlimited)")
+    pathsFlag := flag.Int("paths", 1,
+        "path: number of shortest call paths to print")
+    jsonFlag := flag.Bool("json", false,
+        "callers / callees / path / diff / check: print the answer as JSON")
+
 
     flag.Parse()
-    /* -------------------------------------------------------
-     * Project root detection
-     * ------------------------------------------------------- */
-    projectRoot := GetModuleName(*targetDir)
-    if projectRoot == "" {
-        log.Printf("[warn] could not find go.mod in %s or parents", *targetDir)
-    } else {
-        fmt.Printf("[info] project root: %s\n", projectRoot)
+
+    ifaceMode, err := cs_callgraph.ParseIfaceMode(*ifaceFlag)
+    if err != nil {
+        log.Fatal(err)
+    }
+    funcValMode, err := cs_callgraph.ParseFuncValueMode(*funcValFlag)
+    if err != nil {
+        log.Fatal(err)
+    }
+    genericsMode, err := cs_callgraph.ParseGenericsMode(*genericsFlag)
+    if err != nil {
+        log.Fatal(err)
+    }
+    if *kFlag > 0 && funcValMode != cs_callgraph.FuncValVTA {
+        log.Printf("[warn] -k %d without -funcval vta: calls through function-valued parameters "+
+            "have no edges, so callbacks are not split per context", *kFlag)
+    }
+    sizeBy, err := cs_callgraph.ParseCentralityMetric(*sizeByFlag)
+    if err != nil {
+        log.Fatal(err)
+    }
+    renderer, err := visualisation.ParseRenderer(*rendererFlag)
+    if err != nil {
+        log.Fatal(err)
+    }
+    for _, path := range exportPaths {
+        if _, err := export.FormatFromPath(path); err != nil {
+            log.Fatal(err)
+        }
+    }
+    var ruleSet *rules.RuleSet
+    if *rulesFile != "" {
+        if ruleSet, err = rules.Load(*rulesFile); err != nil {
+            log.Fatal(err)
+        }
+    }
+    var budgets *budget.Set
+    if *budgetFile != "" {
+        if budgets, err = budget.Load(*budgetFile); err != nil {
+            log.Fatal(err)
+        }
+        if *noStats {
+            log.Fatal("-budget needs the statistics; drop -no-stats")
+        }
+    }
+    if *sarifOut != "" && *noStats {
+        log.Fatal("-sarif needs the statistics; drop -no-stats")
+    }
+
+    cfg := callstat.Config{
+        Dir:        *targetDir,
+        Depth:      *depthFlag,
+        NoStdlib:   *noStdlib,
+        SkipCG:     skipCGPatterns,
+        Main:       *mainEntry,
+        AllMains:   *allMains,
+        Inits:      *withInits,
+        Entries:    entryNames,
+        Lib:        *libMode,
+        LibPkgs:    libPatterns,
+        Iface:      ifaceMode,
+        FuncValue:  funcValMode,
+        Generics:   genericsMode,
+        K:          *kFlag,
+        View:       *viewFlag,
+        Sinks:      sinkPatterns,
+        Centrality: *centralityFlag || sizeBy != cs_callgraph.MetricNone,
+        FuncMetrics: *funcMetrics,
+        Rules:      ruleSet,
+        NoStats:    *noStats,
+        Log:        os.Stdout,
+    }
+
+    if command == "diff" {
+        opts := diffOptions{json: *jsonFlag, dotDir: *dotDir, svgDir: *svgDir, renderer: renderer}
+        flag.Visit(func(f *flag.Flag) {
+            if f.Name == "report" {
+                opts.report = *reportOut
+            }
+        })
+        if err := runDiff(flag.Args(), cfg, opts); err != nil {
+            log.Fatal(err)
+        }
+        return
+    }
+    if command == "check" {
+        code, err := runCheck(flag.Args(), cfg, budgets, *jsonFlag)
+        if err != nil {
+            log.Fatal(err)
+        }
+        os.Exit(code)
+    }
+    if command != "" {
+        opts := queryOptions{levels: *levelsFlag, paths: *pathsFlag, json: *jsonFlag}
+        if err := runQuery(command, flag.Args(), cfg, opts); err != nil {
+            log.Fatal(err)
+        }
+        return
     }
 
     /* -------------------------------------------------------
-     * Benchmark loop
+     * Analysis
      * ------------------------------------------------------- */
-    cs_callgraph.InitSTDLib();
-    cs_callgraph.EffectivePkgCache = sync.Map{}
     totalTimeStart := time.Now()
-
-    /* -------------------------------------------------------
-    * Load Packages
-    * ------------------------------------------------------- */
-    t := time.Now()
-    pkgs, err := packages.Load(&packages.Config{
-        Mode: packages.LoadAllSyntax,
-        Dir:  *targetDir,
-    }, "./...")
+    res, err := callstat.Analyze(context.Background(), cfg)
     if err != nil {
         log.Fatal(err)
     }
-    
-    fmt.Printf("[timer] package load  %v\n", time.Since(t))
 
-    /* -------------------------------------------------------
-    * Build SSA
-    * ------------------------------------------------------- */
-    t = time.Now()
-    prog, _ := ssautil.AllPackages(pkgs, ssa.BuilderMode(0))
-    prog.Build()
-    fmt.Printf("[timer] SSA build     %v\n", time.Since(t))
-
-    // - Collect all known package paths for skip expansion
-    allPkgPaths := make([]string, 0, len(prog.AllPackages()))
-    for _, pkg := range prog.AllPackages() {
-        if pkg.Pkg != nil {
-            allPkgPaths = append(allPkgPaths, pkg.Pkg.Path())
-        }
+    if res.ProjectRoot == "" {
+        log.Printf("[warn] could not find go.mod in %s or parents", *targetDir)
+    } else {
+        fmt.Printf("[info] project root: %s\n", res.ProjectRoot)
+    }
+    fmt.Printf("[timer] package load  %v\n", res.Timings.Load)
+    fmt.Printf("[timer] SSA build     %v\n", res.Timings.SSA)
+    fmt.Printf("[timer] callgraph     %v\n", res.Timings.CallGraph)
+    if *kFlag > 0 {
+        fmt.Printf("[timer] contexts      %v\n", res.Timings.Contexts)
+    }
+    if cfg.Centrality {
+        fmt.Printf("[timer] centrality    %v\n", res.Timings.Centrality)
+    }
+    if res.Rules != nil {
+        fmt.Printf("[timer] rules         %v\n", res.Timings.Rules)
+        printRules(res.Rules)
     }
 
     /* -------------------------------------------------------
-    * Callgraph
+    * Statistics
     * ------------------------------------------------------- */
-    t = time.Now()
-    targetMainPkg := cs_callgraph.ResolveMain(prog, projectRoot, *mainEntry)
-    skipCGMap := buildSkipMap(skipCGPatterns, *noStdlib, allPkgPaths)
-    depthMap  := cs_callgraph.BuildPackageDepthMapFromMain(prog, projectRoot, targetMainPkg.Packg)
-    
-    cg        := cs_callgraph.BuildExtendedCallGraph2(
-        prog, *depthFlag, depthMap, skipCGMap,
-    )
-    fmt.Printf("[timer] callgraph     %v\n", time.Since(t))
+    if res.Report != nil {
+        t := time.Now()
+        if err := res.Report.WriteJSONToFile(*statsOut); err != nil {
+            log.Fatal(err)
+        }
+        fmt.Printf("[timer] statistics    %v\n", res.Timings.Stats+time.Since(t))
+    }
+    var budgetResults []*budget.Result
+    if budgets != nil {
+        if budgetResults, err = budget.Evaluate(budgets, res.Report); err != nil {
+            log.Fatal(err)
+        }
+        printBudgets(budgetResults)
+    }
+    if *sarifOut != "" {
+        sarif := export.BuildSARIF(res.View, res.Report, cfg.Dir)
+        if err := export.WriteSARIFFile(*sarifOut, sarif); err != nil {
+            log.Fatal(err)
+        }
+    }
 
     /* -------------------------------------------------------
-    * Statistics
+    * Graph export
     * ------------------------------------------------------- */
-    if !*noStats {
-        t = time.Now()
-        statsObj := stats.GatherCallGraphStats(
-            cg, depthMap, *depthFlag, projectRoot, targetMainPkg.Funct, skipCGMap,
-        )
-        statsObj.WriteJSONToFile(*statsOut)
-        fmt.Printf("[timer] statistics    %v\n", time.Since(t))
+    if len(exportPaths) > 0 {
+        t   := time.Now()
+        doc := export.Build(res.View, res.DepthMap, res.ProjectRoot)
+        for _, path := range exportPaths {
+            if err := export.WriteFile(path, doc); err != nil {
+                log.Fatal(err)
+            }
+        }
+        fmt.Printf("[timer] export        %v\n", time.Since(t))
     }
 
     /* -------------------------------------------------------
     * Visualisation
     * ------------------------------------------------------- */
     if !*noVis {
-        t = time.Now()
-        skipVisMap := buildSkipMap(skipVisPatterns, *noStdlib, allPkgPaths)
+        t := time.Now()
+        skipVisMap := callstat.MatchPackages(skipVisPatterns, *noStdlib, res.Graph.Std, res.PackagePaths)
+        var sizing *visualisation.NodeSizing
+        if sizeBy != cs_callgraph.MetricNone {
+            sizing = &visualisation.NodeSizing{
+                Metric: sizeBy.String(),
+                Values: make(map[*cs_callgraph.Node]float64, len(res.Centrality)),
+            }
+            for n, c := range res.Centrality {
+                sizing.Values[n] = c.Value(sizeBy)
+            }
+        }
         err = visualisation.GenerateHTMLReport(
-            cg, *dotDir, *svgDir, *reportOut,
-            0, skipVisMap, depthMap, *depthFlag, *statsOut, projectRoot,
+            res.View, *dotDir, *svgDir, *reportOut,
+            *workers, skipVisMap, res.DepthMap, *depthFlag, *statsOut, res.ProjectRoot, sizing, renderer, *splitFlag, nil,
         )
         if err != nil {
             log.Fatal(err)
@@ -244,4 +360,9 @@ func main() {
     totalTimeFinished := time.Since(totalTimeStart).Milliseconds()
 
     fmt.Printf("\n[average] %dms", totalTimeFinished)
-}
\ No newline at end of file
+
+    if code := budget.Worst(budgetResults); code != budget.OutcomePass {
+        fmt.Println()
+        os.Exit(int(code))
+    }
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	cs_callgraph "callstat/CS-Callgraph"
+	callstat "callstat/Callstat"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/* ============================================================================
+ * Query subcommands
+ * ----------------------------------------------------------------------------
+ * Answer questions against one built call graph instead of writing reports:
+ *
+ *   callstat callers [flags] <func>       who calls <func> (-levels deep)
+ *   callstat callees [flags] <func>       what <func> calls (-levels deep)
+ *   callstat path    [flags] <from> <to>  the -paths shortest call paths
+ *
+ * Functions are named as for -main ("github.com/you/repo/pkg.Func") or, for
+ * methods, as SSA prints them ("(*github.com/you/repo/pkg.T).Method").
+ * Queries always run on the collapsed graph. All analysis flags apply.
+ * ============================================================================
+ */
+var queryCommands = map[string]int{
+    "callers": 1,
+    "callees": 1,
+    "path":    2,
+}
+
+type queryOptions struct {
+    levels int
+    paths  int
+    json   bool
+}
+
+/* ============================================================================
+ * JSON answers
+ * ============================================================================
+ */
+type queryHop struct {
+    Function  string   `json:"function"`
+    Depth     int      `json:"depth"`
+    Kind      string   `json:"kind"`
+    Positions []string `json:"positions"`
+}
+
+type hopAnswer struct {
+    Command  string     `json:"command"`
+    Function string     `json:"function"`
+    Levels   int        `json:"levels"`
+    Results  []queryHop `json:"results"`
+}
+
+type queryStep struct {
+    Caller    string   `json:"caller"`
+    Callee    string   `json:"callee"`
+    Kind      string   `json:"kind"`
+    Positions []string `json:"positions"`
+}
+
+type pathAnswer struct {
+    Command string        `json:"command"`
+    From    string        `json:"from"`
+    To      string        `json:"to"`
+    Paths   [][]queryStep `json:"paths"`
+}
+
+/* ============================================================================
+ * runQuery
+ * ----------------------------------------------------------------------------
+ * Builds the graph for cfg and prints the answer to stdout. Progress output
+ * of the analysis goes to stderr so the answer (in particular -json) can be
+ * piped as-is.
+ * ============================================================================
+ */
+func runQuery(cmd string, args []string, cfg callstat.Config, opts queryOptions) error {
+    if len(args) != queryCommands[cmd] {
+        if cmd == "path" {
+            return fmt.Errorf("usage: callstat path [flags] <from> <to>")
+        }
+        return fmt.Errorf("usage: callstat %s [flags] <func>", cmd)
+    }
+    res, err := analyzeQuiet(cfg)
+    if err != nil {
+        return err
+    }
+    nodes := make([]*cs_callgraph.Node, len(args))
+    for i, name := range args {
+        if nodes[i], err = res.Graph.FindFunction(name); err != nil {
+            return err
+        }
+    }
+
+    if cmd == "path" {
+        return printPaths(nodes[0], nodes[1], opts)
+    }
+    hops := cs_callgraph.Callees(nodes[0], opts.levels)
+    if cmd == "callers" {
+        hops = cs_callgraph.Callers(nodes[0], opts.levels)
+    }
+    return printHops(cmd, nodes[0], hops, opts)
+}
+
+/* ============================================================================
+ * analyzeQuiet
+ * ----------------------------------------------------------------------------
+ * Runs the analysis without statistics, sending its progress output to
+ * stderr so that stdout carries only the subcommand's answer.
+ * ============================================================================
+ */
+func analyzeQuiet(cfg callstat.Config) (*callstat.Result, error) {
+    cfg.NoStats = true
+    return analyzeSilenced(cfg)
+}
+
+// analyzeSilenced runs cfg as given with Analyze's progress output on stderr
+func analyzeSilenced(cfg callstat.Config) (*callstat.Result, error) {
+    cfg.Log = os.Stderr
+    return callstat.Analyze(context.Background(), cfg)
+}
+
+func printHops(cmd string, n *cs_callgraph.Node, hops []cs_callgraph.Hop, opts queryOptions) error {
+    answer := hopAnswer{
+        Command:  cmd,
+        Function: n.QualifiedName(),
+        Levels:   opts.levels,
+        Results:  []queryHop{},
+    }
+    for _, h := range hops {
+        answer.Results = append(answer.Results, queryHop{
+            Function:  h.Node.QualifiedName(),
+            Depth:     h.Depth,
+            Kind:      h.Edge.Kind.String(),
+            Positions: positions(h.Edge),
+        })
+    }
+    if opts.json {
+        return printJSON(answer)
+    }
+
+    fmt.Printf("%s of %s (%d found):\n", cmd, answer.Function, len(answer.Results))
+    for _, r := range answer.Results {
+        fmt.Printf("  %2d  %-60s  %s %s\n", r.Depth, r.Function, r.Kind, strings.Join(r.Positions, ", "))
+    }
+    return nil
+}
+
+func printPaths(from, to *cs_callgraph.Node, opts queryOptions) error {
+    answer := pathAnswer{
+        Command: "path",
+        From:    from.QualifiedName(),
+        To:      to.QualifiedName(),
+        Paths:   [][]queryStep{},
+    }
+    for _, p := range cs_callgraph.ShortestPaths(from, to, opts.paths) {
+        steps := []queryStep{}
+        for _, e := range p {
+            steps = append(steps, queryStep{
+                Caller:    e.Caller.QualifiedName(),
+                Callee:    e.Callee.QualifiedName(),
+                Kind:      e.Kind.String(),
+                Positions: positions(e),
+            })
+        }
+        answer.Paths = append(answer.Paths, steps)
+    }
+    if opts.json {
+        return printJSON(answer)
+    }
+
+    if len(answer.Paths) == 0 {
+        fmt.Printf("no call path from %s to %s\n", answer.From, answer.To)
+        return nil
+    }
+    for i, p := range answer.Paths {
+        fmt.Printf("path %d (%d calls):\n", i+1, len(p))
+        fmt.Printf("  %s\n", answer.From)
+        for _, s := range p {
+            fmt.Printf("    -[%s]-> %s\n", strings.Join(append([]string{s.Kind}, s.Positions...), " "), s.Callee)
+        }
+    }
+    return nil
+}
+
+func positions(e *cs_callgraph.Edge) []string {
+    result := []string{}
+    for _, p := range e.Positions() {
+        result = append(result, p.String())
+    }
+    return result
+}
+
+func printJSON(v any) error {
+    encoder := json.NewEncoder(os.Stdout)
+    encoder.SetIndent("", "  ")
+    return encoder.Encode(v)
+}